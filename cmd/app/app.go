@@ -5,21 +5,40 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/andrdru/go-template/graceful"
+	"github.com/andrdru/go-template/internal/admin"
 	"github.com/andrdru/go-template/internal/api"
 	"github.com/andrdru/go-template/internal/configs"
+	"github.com/andrdru/go-template/internal/grpcapi"
+	"github.com/andrdru/go-template/internal/health"
+	"github.com/andrdru/go-template/internal/hub"
+	"github.com/andrdru/go-template/internal/instrumented"
+	"github.com/andrdru/go-template/internal/jobs"
+	"github.com/andrdru/go-template/internal/locks"
 	"github.com/andrdru/go-template/internal/managers"
+	"github.com/andrdru/go-template/internal/outbox"
+	"github.com/andrdru/go-template/internal/pagination"
+	"github.com/andrdru/go-template/internal/ratelimit"
 	"github.com/andrdru/go-template/internal/repos"
+	"github.com/andrdru/go-template/internal/ws"
+	"github.com/andrdru/go-template/redis"
+	"github.com/andrdru/go-template/tx"
 )
 
 type (
 	bootstrap struct {
-		httpListenAndServe func()
+		httpListenAndServe  func()
+		adminListenAndServe func()
+		// grpcServe nil if grpc is disabled
+		grpcServe func()
+		// workers background loops, stopped by closers
+		workers []func()
 
 		closers []graceful.Closer
 	}
@@ -54,6 +73,15 @@ func Run(logger *slog.Logger, configPath string) (code int) {
 	}()
 
 	go boot.httpListenAndServe()
+	go boot.adminListenAndServe()
+
+	if boot.grpcServe != nil {
+		go boot.grpcServe()
+	}
+
+	for _, worker := range boot.workers {
+		go worker()
+	}
 
 	logger.Info("app started successfully")
 	<-ctx.Done()
@@ -69,15 +97,202 @@ func initApp(logger *slog.Logger, conf configs.Config) (boot bootstrap, err erro
 		return bootstrap{}, fmt.Errorf("postgres connect: %w", err)
 	}
 
-	userRepo := repos.NewUser(db)
-	authManager := managers.NewAuth(userRepo)
+	var healthOpts []health.Option
+	if conf.Health.Timeout > 0 {
+		healthOpts = append(healthOpts, health.WithTimeout(conf.Health.Timeout))
+	}
+	if conf.Health.CacheTTL > 0 {
+		healthOpts = append(healthOpts, health.WithCacheTTL(conf.Health.CacheTTL))
+	}
+
+	healthChecks := health.NewRegistry(healthOpts...)
+	healthChecks.Register("postgres", db.PingContext)
+
+	var hubOpts []hub.Option
+	if conf.HTTP.SSE.History > 0 {
+		hubOpts = append(hubOpts, hub.WithHistory(conf.HTTP.SSE.History))
+	}
+	if conf.HTTP.SSE.Buffer > 0 {
+		hubOpts = append(hubOpts, hub.WithBuffer(conf.HTTP.SSE.Buffer))
+	}
+
+	eventHub := hub.NewHub(hubOpts...)
+
+	wsOpts := []ws.Option{
+		ws.WithCheckOrigin(ws.AllowedOrigins(conf.HTTP.CORS.AllowedOrigins)),
+	}
+	if conf.HTTP.WebSocket.PingPeriod > 0 {
+		wsOpts = append(wsOpts, ws.WithPingPeriod(conf.HTTP.WebSocket.PingPeriod))
+	}
+	if conf.HTTP.WebSocket.PongTimeout > 0 {
+		wsOpts = append(wsOpts, ws.WithPongTimeout(conf.HTTP.WebSocket.PongTimeout))
+	}
+	if conf.HTTP.WebSocket.WriteTimeout > 0 {
+		wsOpts = append(wsOpts, ws.WithWriteTimeout(conf.HTTP.WebSocket.WriteTimeout))
+	}
+	if conf.HTTP.WebSocket.SendBuffer > 0 {
+		wsOpts = append(wsOpts, ws.WithSendBuffer(conf.HTTP.WebSocket.SendBuffer))
+	}
+	if conf.HTTP.WebSocket.MaxMessageSize > 0 {
+		wsOpts = append(wsOpts, ws.WithMaxMessageSize(conf.HTTP.WebSocket.MaxMessageSize))
+	}
+
+	wsHub := ws.NewHub(logger, wsOpts...)
+
+	replicaDBs, err := conf.Postgres.ConnectReplicas()
+	if err != nil {
+		return bootstrap{}, fmt.Errorf("postgres replicas connect: %w", err)
+	}
+
+	replicas := tx.NewReplicas(replicaDBs...)
+	if len(replicaDBs) > 0 {
+		ctxWatch, cancelWatch := context.WithCancel(context.Background())
+		if conf.Postgres.ReplicaCheckInterval > 0 {
+			go replicas.Watch(ctxWatch, conf.Postgres.ReplicaCheckInterval)
+		}
+
+		boot.closers = append(boot.closers, func(_ context.Context) (description string, err error) {
+			cancelWatch()
+			return "postgres replicas", replicas.Close()
+		})
+	}
+
+	transactor := repos.NewTX(db,
+		tx.WithReplicas(replicas),
+		tx.WithStickiness(conf.Postgres.ReplicaStickiness),
+		tx.WithWrapper(queryWrapper(logger, conf)),
+	)
+
+	userRepo := repos.NewUser(transactor)
+	authOpts := []managers.AuthOption{
+		managers.WithCookieSecure(conf.HTTP.Cookie.Secure),
+		managers.WithCookieSameSite(conf.HTTP.Cookie.SameSiteMode()),
+		managers.WithEvents(eventHub),
+	}
+	if conf.Outbox.Enabled {
+		authOpts = append(authOpts, managers.WithOutbox(transactor, outbox.NewOutbox(repos.NewOutbox(transactor))))
+	}
+
+	authManager := managers.NewAuth(userRepo, authOpts...)
+
+	// redisClient nil if redis is not used
+	var redisClient *redis.Redis
+	if conf.HTTP.RateLimit.Storage == "redis" || (conf.Outbox.Enabled && conf.Outbox.Publisher == "redis") {
+		pool := redis.NewPool(conf.Redis.Address)
+		boot.closers = append(boot.closers, func(_ context.Context) (description string, err error) {
+			return "redis pool", pool.Close()
+		})
+
+		var opts []redis.Option
+		if conf.Redis.Timeout > 0 {
+			opts = append(opts, redis.WithTimeout(conf.Redis.Timeout))
+		}
+
+		redisClient = redis.NewRedis(pool, opts...)
+		healthChecks.Register("redis", redisClient.Ping)
+	}
+
+	var rateLimiter ratelimit.Store = ratelimit.NewMemory()
+	if conf.HTTP.RateLimit.Storage == "redis" {
+		rateLimiter = ratelimit.NewRedis(redisClient)
+	}
+
+	idempotencyManager := managers.NewIdempotency(
+		repos.NewIdempotency(transactor),
+		conf.HTTP.Idempotency.LockTimeout,
+		conf.HTTP.Idempotency.TTL,
+	)
+
+	userManager := managers.NewUser(userRepo)
+
+	httpAPI, err := api.NewAPI(logger, conf, authManager, userManager, rateLimiter, idempotencyManager, eventHub, wsHub)
+	if err != nil {
+		return bootstrap{}, fmt.Errorf("api: %w", err)
+	}
 
-	httpAPI := api.NewAPI(logger, authManager)
 	router := httpAPI.InitRoutes()
 
 	srv := &http.Server{
-		Addr:    fmt.Sprintf("%s:%s", conf.HTTP.Host, conf.HTTP.Port),
-		Handler: router,
+		Addr:              fmt.Sprintf("%s:%s", conf.HTTP.Host, conf.HTTP.Port),
+		Handler:           router,
+		ReadHeaderTimeout: conf.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       conf.HTTP.ReadTimeout,
+		WriteTimeout:      conf.HTTP.WriteTimeout,
+		IdleTimeout:       conf.HTTP.IdleTimeout,
+	}
+
+	if err = initAdmin(&boot, logger, conf, healthChecks); err != nil {
+		return bootstrap{}, err
+	}
+
+	if conf.Outbox.Enabled {
+		var relay *outbox.Relay
+		relay, err = newOutboxRelay(logger, conf.Outbox, transactor, redisClient)
+		if err != nil {
+			return bootstrap{}, fmt.Errorf("outbox relay: %w", err)
+		}
+
+		boot.workers = append(boot.workers, relay.Run)
+
+		// LIFO: stopped after servers, events enqueued by last requests are delivered
+		boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+			return "outbox relay", relay.Stop(ctx)
+		})
+	}
+
+	if conf.Jobs.Enabled {
+		worker := newJobsWorker(logger, conf.Jobs, transactor, userManager)
+		boot.workers = append(boot.workers, worker.Run)
+
+		// LIFO: stopped before outbox relay, jobs may enqueue events
+		boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+			return "jobs worker", worker.Stop(ctx)
+		})
+	}
+
+	if conf.Jobs.ScheduleInterval > 0 {
+		var opts []managers.SchedulerOption
+		if conf.Jobs.SessionsRetention > 0 {
+			opts = append(opts, managers.WithSessionsPurge(conf.Jobs.SessionsRetention))
+		}
+		if conf.Jobs.JobsRetention > 0 {
+			opts = append(opts, managers.WithJobsPurge(conf.Jobs.JobsRetention))
+		}
+		if conf.Jobs.OutboxRetention > 0 {
+			opts = append(opts, managers.WithOutboxPurge(conf.Jobs.OutboxRetention))
+		}
+
+		scheduler := managers.NewScheduler(logger, jobs.NewQueue(repos.NewJobs(transactor)),
+			conf.Jobs.ScheduleInterval, opts...)
+
+		// one of app replicas schedules jobs
+		leader := locks.NewLeader(logger, db, "jobs.scheduler", scheduler.Run)
+		boot.workers = append(boot.workers, leader.Run)
+
+		boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+			return "jobs scheduler", leader.Stop(ctx)
+		})
+	}
+
+	if conf.GRPC.Enabled {
+		var lis net.Listener
+		lis, err = net.Listen("tcp", fmt.Sprintf("%s:%s", conf.GRPC.Host, conf.GRPC.Port))
+		if err != nil {
+			return bootstrap{}, fmt.Errorf("grpc listen: %w", err)
+		}
+
+		grpcServer := grpcapi.NewServer(logger, conf.GRPC,
+			pagination.NewPaginator(conf.HTTP.Pagination.CursorSecret), authManager, userManager, rateLimiter)
+
+		boot.grpcServe = func() {
+			if errServe := grpcServer.Serve(lis); errServe != nil {
+				logger.Error("serve grpc", slog.Any("error", errServe))
+			}
+		}
+
+		boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+			return "grpc server", grpcServer.Stop(ctx)
+		})
 	}
 
 	boot.httpListenAndServe = func() {
@@ -91,5 +306,134 @@ func initApp(logger *slog.Logger, conf configs.Config) (boot bootstrap, err erro
 		return "http server", err
 	})
 
+	// LIFO: end open streams first, srv.Shutdown waits for them until timeout otherwise
+	boot.closers = append(boot.closers, func(_ context.Context) (description string, err error) {
+		eventHub.Close()
+		return "event hub", nil
+	})
+
+	// hijacked connections are not tracked by srv.Shutdown
+	boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+		return "websocket hub", wsHub.Close(ctx)
+	})
+
+	// runs first: stop routing traffic to pod before anything is closed
+	boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+		healthChecks.Shutdown()
+
+		select {
+		case <-time.After(conf.Health.ShutdownDelay):
+		case <-ctx.Done():
+		}
+
+		return "readiness", nil
+	})
+
 	return boot, nil
 }
+
+// newOutboxRelay relay with publisher by config
+func newOutboxRelay(
+	logger *slog.Logger,
+	conf configs.Outbox,
+	transactor *tx.TX,
+	redisClient *redis.Redis,
+) (*outbox.Relay, error) {
+	var publisher outbox.Publisher
+	switch conf.Publisher {
+	case "", "log":
+		publisher = outbox.NewLogPublisher(logger)
+	case "webhook":
+		if conf.Webhook.URL == "" {
+			return nil, errors.New("webhook url is empty")
+		}
+
+		publisher = outbox.NewWebhook(conf.Webhook.URL, outbox.WithWebhookSecret(conf.Webhook.Secret))
+	case "redis":
+		publisher = outbox.NewRedisStream(redisClient,
+			outbox.WithStreamPrefix(conf.RedisStream.Prefix),
+			outbox.WithStreamMaxLen(conf.RedisStream.MaxLen),
+		)
+	default:
+		return nil, fmt.Errorf("unknown publisher %q", conf.Publisher)
+	}
+
+	var opts []outbox.RelayOption
+	if conf.PollInterval > 0 {
+		opts = append(opts, outbox.WithPollInterval(conf.PollInterval))
+	}
+	if conf.BatchSize > 0 {
+		opts = append(opts, outbox.WithBatchSize(conf.BatchSize))
+	}
+	if conf.MaxAttempts > 0 {
+		opts = append(opts, outbox.WithMaxAttempts(conf.MaxAttempts))
+	}
+	if conf.BackoffBase > 0 && conf.BackoffMax > 0 {
+		opts = append(opts, outbox.WithBackoff(conf.BackoffBase, conf.BackoffMax))
+	}
+	if conf.PublishTimeout > 0 {
+		opts = append(opts, outbox.WithPublishTimeout(conf.PublishTimeout))
+	}
+	if conf.Lease > 0 {
+		opts = append(opts, outbox.WithLease(conf.Lease))
+	}
+
+	return outbox.NewRelay(logger, repos.NewOutbox(transactor), publisher, opts...), nil
+}
+
+// initAdmin admin http server with health, metrics and pprof
+func initAdmin(boot *bootstrap, logger *slog.Logger, conf configs.Config, healthChecks *health.Registry) error {
+	adminRouter, err := admin.NewRouter(conf.Admin, healthChecks)
+	if err != nil {
+		return fmt.Errorf("admin router: %w", err)
+	}
+
+	adminSrv := &http.Server{
+		Addr:              fmt.Sprintf("%s:%s", conf.Admin.Host, conf.Admin.Port),
+		Handler:           adminRouter,
+		ReadHeaderTimeout: conf.HTTP.ReadHeaderTimeout,
+	}
+
+	boot.adminListenAndServe = func() {
+		if errServe := adminSrv.ListenAndServe(); errServe != nil && !errors.Is(errServe, http.ErrServerClosed) {
+			logger.Error("serve admin http", slog.Any("error", errServe))
+		}
+	}
+
+	// LIFO: closed last, metrics and probes are served during shutdown
+	boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+		return "admin http server", adminSrv.Shutdown(ctx)
+	})
+
+	return nil
+}
+
+// queryWrapper instrument repos queries
+func queryWrapper(logger *slog.Logger, conf configs.Config) func(db tx.QueryExecutor) tx.QueryExecutor {
+	return instrumented.Wrap(
+		instrumented.WithLogger(logger),
+		instrumented.WithSlowThreshold(conf.Postgres.SlowQueryThreshold),
+	)
+}
+
+// newJobsWorker worker with app jobs handlers
+func newJobsWorker(logger *slog.Logger, conf configs.Jobs, transactor *tx.TX, userManager *managers.User) *jobs.Worker {
+	var opts []jobs.WorkerOption
+	if conf.Concurrency > 0 {
+		opts = append(opts, jobs.WithConcurrency(conf.Concurrency))
+	}
+	if conf.PollInterval > 0 {
+		opts = append(opts, jobs.WithPollInterval(conf.PollInterval))
+	}
+	if conf.VisibilityTimeout > 0 {
+		opts = append(opts, jobs.WithVisibilityTimeout(conf.VisibilityTimeout))
+	}
+	if conf.BackoffBase > 0 && conf.BackoffMax > 0 {
+		opts = append(opts, jobs.WithBackoff(conf.BackoffBase, conf.BackoffMax))
+	}
+
+	worker := jobs.NewWorker(logger, repos.NewJobs(transactor), opts...)
+	managers.RegisterJobs(worker, userManager, outbox.NewOutbox(repos.NewOutbox(transactor)))
+
+	return worker
+}
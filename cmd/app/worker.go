@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/andrdru/go-template/graceful"
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/andrdru/go-template/internal/health"
+	"github.com/andrdru/go-template/internal/managers"
+	"github.com/andrdru/go-template/internal/repos"
+	"github.com/andrdru/go-template/tx"
+)
+
+// RunWorker jobs worker without http and grpc servers
+// admin server is served for probes and metrics
+func RunWorker(logger *slog.Logger, configPath string) (code int) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("worker starting")
+	defer logger.Info("worker finished")
+
+	conf, err := configs.NewConfig(configPath)
+	if err != nil {
+		logger.Error("init config", slog.Any("error", err))
+		return 1
+	}
+
+	boot, err := initWorker(logger, conf)
+	if err != nil {
+		logger.Error("init worker", slog.Any("error", err))
+		return 1
+	}
+
+	defer func() {
+		ctxCloser, cancelCloser := context.WithTimeout(context.Background(), 15*time.Second)
+		defer func() {
+			cancelCloser()
+		}()
+
+		graceful.Stop(ctxCloser, logger, boot.closers)
+	}()
+
+	go boot.adminListenAndServe()
+
+	for _, worker := range boot.workers {
+		go worker()
+	}
+
+	logger.Info("worker started successfully")
+	<-ctx.Done()
+
+	return 0
+}
+
+func initWorker(logger *slog.Logger, conf configs.Config) (boot bootstrap, err error) {
+	boot = bootstrap{}
+
+	db, err := conf.Postgres.Connect()
+	if err != nil {
+		return bootstrap{}, fmt.Errorf("postgres connect: %w", err)
+	}
+
+	healthChecks := health.NewRegistry()
+	healthChecks.Register("postgres", db.PingContext)
+
+	// jobs write, replicas are not used
+	transactor := repos.NewTX(db, tx.WithWrapper(queryWrapper(logger, conf)))
+
+	userManager := managers.NewUser(repos.NewUser(transactor))
+
+	if err = initAdmin(&boot, logger, conf, healthChecks); err != nil {
+		return bootstrap{}, err
+	}
+
+	worker := newJobsWorker(logger, conf.Jobs, transactor, userManager)
+	boot.workers = append(boot.workers, worker.Run)
+
+	boot.closers = append(boot.closers, func(ctx context.Context) (description string, err error) {
+		return "jobs worker", worker.Stop(ctx)
+	})
+
+	return boot, nil
+}
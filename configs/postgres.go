@@ -23,6 +23,19 @@ type (
 		Pass    string  `yaml:"pass"`
 		Dbname  string  `yaml:"dbname"`
 		AppName *string `yaml:"appname"`
+		// Replicas read replicas, user, pass and dbname are same as primary
+		Replicas []PostgresReplica `yaml:"replicas"`
+		// ReplicaStickiness reads of client go to primary for this long after its write
+		ReplicaStickiness time.Duration `yaml:"replica_stickiness"`
+		// ReplicaCheckInterval replicas health check interval
+		ReplicaCheckInterval time.Duration `yaml:"replica_check_interval"`
+		// SlowQueryThreshold longer queries are logged, zero disables
+		SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	}
+
+	PostgresReplica struct {
+		Host string `yaml:"host"`
+		Port int64  `yaml:"port"`
 	}
 )
 
@@ -36,6 +49,28 @@ var _ DB = &Postgres{}
 
 // Connect raw sql connect
 func (p *Postgres) Connect() (db *sql.DB, err error) {
+	return p.connect(p.Host, p.Port)
+}
+
+// ConnectReplicas raw sql connect to every replica
+func (p *Postgres) ConnectReplicas() (dbs []*sql.DB, err error) {
+	for _, replica := range p.Replicas {
+		db, err := p.connect(replica.Host, replica.Port)
+		if err != nil {
+			for _, opened := range dbs {
+				_ = opened.Close()
+			}
+
+			return nil, fmt.Errorf("replica %s:%d: %w", replica.Host, replica.Port, err)
+		}
+
+		dbs = append(dbs, db)
+	}
+
+	return dbs, nil
+}
+
+func (p *Postgres) connect(host string, port int64) (db *sql.DB, err error) {
 	var appName, _ = os.Executable()
 	if p.AppName != nil {
 		appName = *p.AppName
@@ -43,8 +78,8 @@ func (p *Postgres) Connect() (db *sql.DB, err error) {
 
 	connStr := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s application_name=%s sslmode=disable",
-		p.Host,
-		p.Port,
+		host,
+		port,
 		p.User,
 		p.Pass,
 		p.Dbname,
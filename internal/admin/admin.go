@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/andrdru/go-template/internal/middlewares"
+)
+
+type (
+	healthChecks interface {
+		LiveHandler() http.Handler
+		ReadyHandler() http.Handler
+	}
+)
+
+// NewRouter operational endpoints for admin listener: health, metrics, pprof.
+// Health is not restricted, probes come from node IPs without credentials
+func NewRouter(conf configs.Admin, health healthChecks) (*httprouter.Router, error) {
+	allowed, err := middlewares.ParseIPAllowlist(conf.AllowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("allowed ips: %w", err)
+	}
+
+	restricted := []middlewares.HTTPMiddleware{
+		middlewares.IPAllowlist(allowed, handleForbidden),
+		middlewares.BasicAuth(conf.BasicAuth.Username, conf.BasicAuth.Password, handleUnauthorized),
+	}
+
+	router := httprouter.New()
+
+	// /health kept for existing probes
+	router.GET("/health", httpHandlerAdapterFunc(health.LiveHandler()))
+	router.GET("/health/live", httpHandlerAdapterFunc(health.LiveHandler()))
+	router.GET("/health/ready", httpHandlerAdapterFunc(health.ReadyHandler()))
+
+	handle := func(path string, h http.Handler) {
+		router.GET(path, middlewares.HTTPRouterChain(httpHandlerAdapterFunc(h), restricted...))
+	}
+
+	handle("/metrics", promhttp.Handler())
+
+	handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
+	handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
+	handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+	handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
+	handle("/debug/pprof/heap", pprof.Handler("heap"))
+	handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+	handle("/debug/pprof/block", pprof.Handler("block"))
+	handle("/debug/pprof/mutex", pprof.Handler("mutex"))
+	handle("/debug/pprof/allocs", pprof.Handler("allocs"))
+
+	return router, nil
+}
+
+// httpHandlerAdapterFunc httprouter adapter for http.Handler
+func httpHandlerAdapterFunc(handler http.Handler) httprouter.Handle {
+	return func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		handler.ServeHTTP(writer, request)
+	}
+}
+
+func handleUnauthorized(w http.ResponseWriter, _ string) error {
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	return nil
+}
+
+func handleForbidden(w http.ResponseWriter, _ string) error {
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	return nil
+}
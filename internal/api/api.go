@@ -2,84 +2,223 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
-	"net/http/pprof"
+	"net/netip"
+	"runtime/debug"
 
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/andrdru/go-template/internal/ctxreqid"
 	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/hub"
+	"github.com/andrdru/go-template/internal/managers"
+	"github.com/andrdru/go-template/internal/metrics"
 	"github.com/andrdru/go-template/internal/middlewares"
+	"github.com/andrdru/go-template/internal/pagination"
+	"github.com/andrdru/go-template/internal/ratelimit"
+	"github.com/andrdru/go-template/internal/ws"
 	"github.com/julienschmidt/httprouter"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type (
 	API struct {
 		logger *slog.Logger
+		conf   configs.Config
 
 		authManager authManager
+		userManager userManager
+		paginator   *pagination.Paginator
+		rateLimiter ratelimit.Store
+		idempotency idempotency
+		hub         streamHub
+		ws          wsHub
+		// trustedProxies may set client ip header
+		trustedProxies []netip.Prefix
 	}
 
 	authManager interface {
 		Check(r *http.Request) (ctx context.Context, err error)
 		Login(ctx context.Context, w http.ResponseWriter, session entities.Session) error
 	}
+
+	userManager interface {
+		List(ctx context.Context, params pagination.Params) ([]entities.User, error)
+	}
+
+	idempotency interface {
+		Begin(ctx context.Context, key string, fingerprint string) (lockToken string, resp *entities.IdempotencyResponse, err error)
+		Complete(ctx context.Context, key string, lockToken string, resp entities.IdempotencyResponse) error
+		Release(ctx context.Context, key string, lockToken string) error
+	}
+
+	streamHub interface {
+		Subscribe(topic string, lastEventID uint64) (*hub.Subscription, error)
+	}
+
+	wsHub interface {
+		Serve(w http.ResponseWriter, r *http.Request, userID int64) (*ws.Conn, error)
+	}
 )
 
 var (
 	OptInternalError = Error("internal error")
 	OptUnauthorized  = Error("unauthorized")
+	OptForbidden     = Error("forbidden")
+	OptTooMany       = Error("too many requests")
+	OptTooLarge      = Error("request too large")
+	OptTimeout       = Error("request timeout")
 )
 
-func NewAPI(logger *slog.Logger, sessionManager authManager) *API {
+func NewAPI(
+	logger *slog.Logger,
+	conf configs.Config,
+	sessionManager authManager,
+	userManager userManager,
+	rateLimiter ratelimit.Store,
+	idempotency idempotency,
+	streamHub streamHub,
+	wsHub wsHub,
+) (*API, error) {
+	trustedProxies, err := middlewares.ParseIPAllowlist(conf.HTTP.RateLimit.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit trusted proxies: %w", err)
+	}
+
 	return &API{
 		logger:      logger,
+		conf:        conf,
 		authManager: sessionManager,
-	}
+		userManager: userManager,
+		paginator:   pagination.NewPaginator(conf.HTTP.Pagination.CursorSecret),
+		rateLimiter: rateLimiter,
+		idempotency: idempotency,
+		hub:         streamHub,
+		ws:          wsHub,
+
+		trustedProxies: trustedProxies,
+	}, nil
 }
 
 func (a *API) InitRoutes() *httprouter.Router {
-	router := initHTTP()
+	router := httprouter.New()
+	router.PanicHandler = a.handlePanic
+	router.GlobalOPTIONS = middlewares.CORSPreflight(a.conf.HTTP.CORS)
 
 	auth := []middlewares.HTTPMiddleware{
 		middlewares.SessionValidate(a.authManager, handleUnauthorized),
 	}
 
+	admin := []middlewares.HTTPMiddleware{
+		middlewares.SessionValidate(a.authManager, handleUnauthorized),
+		middlewares.AdminOnly(handleForbidden),
+	}
+
 	// anonymous methods
-	router.Handle(http.MethodPost, "/user/authorize", a.UserAuthorize)
+	a.handle(router, http.MethodPost, "/user/authorize", a.UserAuthorize)
 
 	// auth methods
-	router.Handle(http.MethodGet, "/user/:id", middlewares.HTTPRouterChain(a.UserGet, auth...))
+	a.handle(router, http.MethodGet, "/user/:id", a.UserGet, auth...)
+	a.handleStream(router, http.MethodGet, "/events", a.UserEvents, auth...)
+	a.handleStream(router, http.MethodGet, "/ws", a.UserWebSocket, auth...)
+
+	// admin methods
+	a.handle(router, http.MethodGet, "/users", a.UserList, admin...)
 
 	return router
 }
 
-func initHTTP() *httprouter.Router {
-	router := httprouter.New()
+// handle register route with global middlewares
+func (a *API) handle(
+	router *httprouter.Router,
+	method string,
+	path string,
+	h httprouter.Handle,
+	mws ...middlewares.HTTPMiddleware,
+) {
+	router.Handle(method, path, middlewares.HTTPRouterChain(h, a.chain(method, path, false, mws)...))
+}
 
-	router.GET("/health", func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-		_, _ = w.Write([]byte("ok"))
-	})
+// handleStream register long-living streaming route
+// without timeout, compression and other response buffering
+func (a *API) handleStream(
+	router *httprouter.Router,
+	method string,
+	path string,
+	h httprouter.Handle,
+	mws ...middlewares.HTTPMiddleware,
+) {
+	router.Handle(method, path, middlewares.HTTPRouterChain(h, a.chain(method, path, true, mws)...))
+}
 
-	router.GET("/metrics", httpHandlerAdapterFunc(promhttp.Handler()))
+// idempotencyExcluded routes issuing session cookie:
+// replay skips Set-Cookie, so replayed login would succeed without session
+var idempotencyExcluded = map[string]struct{}{
+	http.MethodPost + " /user/authorize": {},
+}
 
-	router.HandlerFunc(http.MethodGet, "/debug/pprof/", pprof.Index)
-	router.HandlerFunc(http.MethodGet, "/debug/pprof/cmdline", pprof.Cmdline)
-	router.HandlerFunc(http.MethodGet, "/debug/pprof/profile", pprof.Profile)
-	router.HandlerFunc(http.MethodGet, "/debug/pprof/symbol", pprof.Symbol)
-	router.HandlerFunc(http.MethodGet, "/debug/pprof/trace", pprof.Trace)
-	router.Handler(http.MethodGet, "/debug/pprof/goroutine", pprof.Handler("goroutine"))
-	router.Handler(http.MethodGet, "/debug/pprof/heap", pprof.Handler("heap"))
-	router.Handler(http.MethodGet, "/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
-	router.Handler(http.MethodGet, "/debug/pprof/block", pprof.Handler("block"))
+// chain global and route middlewares
+func (a *API) chain(method string, path string, stream bool, mws []middlewares.HTTPMiddleware) []middlewares.HTTPMiddleware {
+	route := method + " " + path
 
-	return router
-}
+	chain := []middlewares.HTTPMiddleware{
+		middlewares.RequestID(),
+		middlewares.HTTPMetrics(a.logger, path, a.conf.HTTP.AccessLogSampling),
+		middlewares.Recover(a.handlePanic),
+	}
+
+	if !stream {
+		chain = append(chain, middlewares.Compress(a.conf.HTTP.Compression))
+	}
+
+	chain = append(chain,
+		middlewares.SecurityHeaders(a.conf.HTTP.SecurityHeaders),
+		middlewares.CORS(a.conf.HTTP.CORS),
+		middlewares.CSRF(a.conf.HTTP.CSRF, a.conf.HTTP.Cookie, managers.CookieUserSession, handleForbidden),
+	)
+
+	if policy, ok := a.conf.HTTP.CacheControl[route]; ok {
+		chain = append(chain, middlewares.CacheControl(policy))
+	}
 
-// httpHandlerAdapterFunc httprouter adapter for http.Handler
-func httpHandlerAdapterFunc(handler http.Handler) httprouter.Handle {
-	return func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-		handler.ServeHTTP(writer, request)
+	bodyLimit, ok := a.conf.HTTP.BodyLimit.Routes[route]
+	if !ok {
+		bodyLimit = a.conf.HTTP.BodyLimit.Default
 	}
+
+	chain = append(chain, middlewares.BodyLimit(bodyLimit, handleTooLarge))
+
+	if !stream {
+		timeout, ok := a.conf.HTTP.Timeout.Routes[route]
+		if !ok {
+			timeout = a.conf.HTTP.Timeout.Default
+		}
+
+		chain = append(chain,
+			middlewares.Timeout(timeout, handleTimeout),
+			middlewares.Fields(),
+		)
+	}
+
+	chain = append(chain, mws...)
+
+	// after route middlewares: limit by user requires session
+	if a.conf.HTTP.RateLimit.Enabled {
+		rule, ok := a.conf.HTTP.RateLimit.Routes[route]
+		if !ok {
+			rule = a.conf.HTTP.RateLimit.Default
+		}
+
+		chain = append(chain, middlewares.RateLimit(a.rateLimiter, route, rule, a.trustedProxies,
+			a.conf.HTTP.RateLimit.APIKeys, handleTooMany))
+	}
+
+	_, noIdempotency := idempotencyExcluded[route]
+	if a.conf.HTTP.Idempotency.Enabled && method == http.MethodPost && !stream && !noIdempotency {
+		chain = append(chain, middlewares.Idempotency(a.idempotency, path, handleError))
+	}
+
+	return chain
 }
 
 func handleUnauthorized(w http.ResponseWriter, message string) error {
@@ -91,3 +230,87 @@ func handleUnauthorized(w http.ResponseWriter, message string) error {
 
 	return m.Return(w)
 }
+
+func handleForbidden(w http.ResponseWriter, message string) error {
+	m := NewMessage()
+	m.SetError(Code(http.StatusForbidden), OptForbidden)
+	if message != "" {
+		m.SetError(Error(message))
+	}
+
+	return m.Return(w)
+}
+
+func handleTooMany(w http.ResponseWriter, message string) error {
+	m := NewMessage()
+	m.SetError(Code(http.StatusTooManyRequests), OptTooMany)
+	if message != "" {
+		m.SetError(Error(message))
+	}
+
+	return m.Return(w)
+}
+
+func handleError(w http.ResponseWriter, code int, message string) error {
+	m := NewMessage()
+	m.SetError(Code(code))
+	if message != "" {
+		m.SetError(Error(message))
+	}
+
+	return m.Return(w)
+}
+
+func handleTooLarge(w http.ResponseWriter, message string) error {
+	m := NewMessage()
+	m.SetError(Code(http.StatusRequestEntityTooLarge), OptTooLarge)
+	if message != "" {
+		m.SetError(Error(message))
+	}
+
+	return m.Return(w)
+}
+
+func handleTimeout(w http.ResponseWriter, message string) error {
+	m := NewMessage()
+	m.SetError(Code(http.StatusServiceUnavailable), OptTimeout)
+	if message != "" {
+		m.SetError(Error(message))
+	}
+
+	return m.Return(w)
+}
+
+// handlePanic log recovered panic and return internal error
+// stack is returned to client in debug mode only
+func (a *API) handlePanic(w http.ResponseWriter, r *http.Request, rcv any) {
+	stack := string(debug.Stack())
+
+	// panic of handler goroutine, see middlewares.Timeout
+	if p, ok := rcv.(middlewares.HandlerPanic); ok {
+		rcv = p.Value
+		stack = string(p.Stack)
+	}
+
+	metrics.CounterPanics("http").Inc()
+
+	a.logger.Error("panic",
+		slog.String("request_id", ctxreqid.Get(r.Context())),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Any("error", rcv),
+		slog.String("stack", stack),
+	)
+
+	m := NewMessage()
+	m.SetError(OptInternalError)
+	if a.conf.IsDebug {
+		m.SetError(MapError("panic", fmt.Sprint(rcv)))
+		m.SetError(MapError("stack", stack))
+	}
+
+	err := m.Return(w)
+	if err != nil {
+		a.logger.Error("write panic response", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	CacheOptions struct {
+		etag         string
+		bodyETag     bool
+		lastModified time.Time
+		cacheControl string
+	}
+
+	CacheOption func(*CacheOptions)
+)
+
+// ReturnCached write successful GET/HEAD response honoring conditional request headers:
+// If-None-Match, If-Modified-Since. Responds 304 if client copy is fresh.
+// Other requests and errors are written as Return does
+func (m *Message) ReturnCached(w http.ResponseWriter, r *http.Request, options ...CacheOption) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead || m.ErrorCode != http.StatusOK {
+		return m.Return(w)
+	}
+
+	var args = &CacheOptions{}
+
+	var opt CacheOption
+	for _, opt = range options {
+		opt(args)
+	}
+
+	data, err := m.marshal(w)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	// requested fields are invalid
+	if m.ErrorCode != http.StatusOK {
+		return m.write(w, data)
+	}
+
+	etag := args.etag
+	if etag == "" && args.bodyETag {
+		sum := sha256.Sum256(data)
+		etag = strconv.Quote(hex.EncodeToString(sum[:16]))
+	}
+
+	h := w.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !args.lastModified.IsZero() {
+		h.Set("Last-Modified", args.lastModified.UTC().Format(http.TimeFormat))
+	}
+	if args.cacheControl != "" {
+		h.Set("Cache-Control", args.cacheControl)
+	}
+
+	if notModified(r, etag, args.lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	return m.write(w, data)
+}
+
+// VersionETag strong etag from entity version, e.g. updated_at
+func VersionETag(updatedAt time.Time) string {
+	return strconv.Quote(strconv.FormatInt(updatedAt.UnixNano(), 36))
+}
+
+// ETag set explicit etag, see VersionETag
+func ETag(etag string) CacheOption {
+	return func(args *CacheOptions) {
+		args.etag = etag
+	}
+}
+
+// BodyETag compute etag from serialized body, if ETag is not set
+func BodyETag() CacheOption {
+	return func(args *CacheOptions) {
+		args.bodyETag = true
+	}
+}
+
+func LastModified(t time.Time) CacheOption {
+	return func(args *CacheOptions) {
+		args.lastModified = t
+	}
+}
+
+func CacheControl(policy string) CacheOption {
+	return func(args *CacheOptions) {
+		args.cacheControl = policy
+	}
+}
+
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	// If-None-Match takes precedence, RFC 9110 13.2.2
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && etagMatch(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	// header precision is seconds
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// etagMatch check etag in If-None-Match list with weak comparison, RFC 9110 13.1.2, W/ prefix is ignored
+func etagMatch(header string, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return etag != ""
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
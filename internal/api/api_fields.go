@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type (
+	// fieldsTree requested fields by path segments, empty tree selects whole value
+	fieldsTree map[string]fieldsTree
+
+	// fieldsScoper select fields inside scope key, other keys are kept
+	fieldsScoper interface {
+		fieldsScope() string
+	}
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// fieldsScope list response fields select items fields, next_cursor is kept
+func (ListResponse[T]) fieldsScope() string {
+	return "items"
+}
+
+// selectFields trim marshaled data to requested fields
+// fields are validated against data type, arrays are transparent
+func selectFields(data any, marshaled []byte, fields []string) ([]byte, error) {
+	typ := reflect.TypeOf(data)
+	scope := ""
+	if scoper, ok := data.(fieldsScoper); ok {
+		scope = scoper.fieldsScope()
+		typ = scopeType(typ, scope)
+	}
+
+	tree := fieldsTree{}
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+
+		if !fieldsValid(typ, path) {
+			return nil, fmt.Errorf("unknown field %s", field)
+		}
+
+		tree.add(path)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(marshaled))
+	decoder.UseNumber()
+
+	var value any
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	if scope != "" {
+		if obj, ok := value.(map[string]any); ok {
+			obj[scope] = tree.prune(obj[scope])
+		}
+	} else {
+		value = tree.prune(value)
+	}
+
+	return json.Marshal(value)
+}
+
+func (t fieldsTree) add(path []string) {
+	node := t
+	for _, segment := range path {
+		child, ok := node[segment]
+		if !ok {
+			child = fieldsTree{}
+			node[segment] = child
+		}
+
+		node = child
+	}
+}
+
+func (t fieldsTree) prune(value any) any {
+	if len(t) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(t))
+		for key, child := range t {
+			if item, ok := v[key]; ok {
+				obj[key] = child.prune(item)
+			}
+		}
+
+		return obj
+	case []any:
+		for i := range v {
+			v[i] = t.prune(v[i])
+		}
+
+		return v
+	default:
+		return value
+	}
+}
+
+// fieldsValid check path by json names of struct fields
+func fieldsValid(typ reflect.Type, path []string) bool {
+	for _, segment := range path {
+		typ = indirectType(typ)
+		if typ == nil {
+			return true
+		}
+
+		// custom marshaling, shape is unknown
+		if typ.Implements(jsonMarshalerType) || reflect.PointerTo(typ).Implements(jsonMarshalerType) {
+			return false
+		}
+
+		switch typ.Kind() {
+		case reflect.Map, reflect.Interface:
+			return true
+		case reflect.Struct:
+			field, ok := jsonField(typ, segment)
+			if !ok {
+				return false
+			}
+
+			typ = field.Type
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// indirectType deref pointers and arrays
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ != nil {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array:
+			typ = typ.Elem()
+		default:
+			return typ
+		}
+	}
+
+	return nil
+}
+
+func jsonField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+
+		if tag == "" && field.Anonymous {
+			embedded := indirectType(field.Type)
+			if embedded != nil && embedded.Kind() == reflect.Struct {
+				if found, ok := jsonField(embedded, name); ok {
+					return found, true
+				}
+			}
+
+			continue
+		}
+
+		if tag == "" {
+			tag = field.Name
+		}
+
+		if tag == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func scopeType(typ reflect.Type, scope string) reflect.Type {
+	typ = indirectType(typ)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return typ
+	}
+
+	field, ok := jsonField(typ, scope)
+	if !ok {
+		return typ
+	}
+
+	return field.Type
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/andrdru/go-template/internal/pagination"
+)
+
+type (
+	// ListResponse list endpoints response
+	// swagger:model
+	ListResponse[T any] struct {
+		Items []T `json:"items"`
+		// NextCursor null on last page
+		NextCursor *string `json:"next_cursor"`
+	}
+)
+
+// NewListResponse trim extra item fetched to detect next page, build next cursor by last item
+func NewListResponse[T any](items []T, limit int, next func(last T) string) ListResponse[T] {
+	resp := ListResponse[T]{
+		Items: items,
+	}
+
+	if len(items) > limit {
+		resp.Items = items[:limit]
+		cursor := next(resp.Items[limit-1])
+		resp.NextCursor = &cursor
+	}
+
+	if resp.Items == nil {
+		resp.Items = []T{}
+	}
+
+	return resp
+}
+
+// readListParams parse list query, set message error if invalid
+func (a *API) readListParams(r *http.Request, spec pagination.Spec, message *Message) (params pagination.Params, ok bool) {
+	params, err := a.paginator.Parse(r.URL.Query(), spec)
+	if err != nil {
+		var fieldErr *pagination.FieldError
+		if errors.As(err, &fieldErr) {
+			message.SetError(Code(http.StatusBadRequest), MapError(fieldErr.Field, fieldErr.Message))
+			return pagination.Params{}, false
+		}
+
+		message.SetError(Code(http.StatusBadRequest), Error(err.Error()))
+		return pagination.Params{}, false
+	}
+
+	return params, true
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 )
 
 type (
@@ -16,6 +17,8 @@ type (
 
 var (
 	ErrInvalidJson = errors.New("json invalid")
+
+	ErrBodyTooLarge = errors.New("body too large")
 )
 
 func ReadRequest(body io.ReadCloser, req Request) error {
@@ -25,6 +28,11 @@ func ReadRequest(body io.ReadCloser, req Request) error {
 
 	data, err := io.ReadAll(body)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("read: %s: %w", err.Error(), ErrBodyTooLarge)
+		}
+
 		return fmt.Errorf("read: %w", err)
 	}
 
@@ -35,3 +43,12 @@ func ReadRequest(body io.ReadCloser, req Request) error {
 
 	return nil
 }
+
+// ReadRequestCode response code for ReadRequest error
+func ReadRequestCode(err error) int {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
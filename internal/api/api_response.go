@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/andrdru/go-template/internal/middlewares"
 )
 
 type (
@@ -71,11 +73,40 @@ func (m *Message) MarshalJSON() ([]byte, error) {
 }
 
 func (m *Message) Return(w http.ResponseWriter) error {
-	var data, err = m.MarshalJSON()
+	var data, err = m.marshal(w)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
 
+	return m.write(w, data)
+}
+
+// marshal message, successful response is trimmed to fields requested with middlewares.Fields.
+// unknown field turns message to bad request error
+func (m *Message) marshal(w http.ResponseWriter) ([]byte, error) {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := middlewares.SelectedFields(w)
+	if len(fields) == 0 || m.ErrorCode != http.StatusOK {
+		return data, nil
+	}
+
+	selected, err := selectFields(m.Data, data, fields)
+	if err != nil {
+		m.Data = nil
+		m.SetError(Code(http.StatusBadRequest), MapError(middlewares.QueryFields, err.Error()))
+
+		return m.MarshalJSON()
+	}
+
+	return selected, nil
+}
+
+// write marshaled message
+func (m *Message) write(w http.ResponseWriter, data []byte) (err error) {
 	w.Header().Add("Content-Type", "application/json")
 
 	// code should not be 0
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andrdru/go-template/internal/hub"
+)
+
+const (
+	// HeaderLastEventID resume stream after this event
+	HeaderLastEventID = "Last-Event-ID"
+
+	// SSEHeartbeatDefault interval of comments keeping idle stream alive through proxies
+	SSEHeartbeatDefault = 15 * time.Second
+)
+
+// Stream send topic events as Server-Sent Events until client disconnects or hub is closed.
+// Events after Last-Event-ID header are replayed if still kept by hub.
+// route must be registered with handleStream
+func (a *API) Stream(w http.ResponseWriter, r *http.Request, topic string) error {
+	rc := http.NewResponseController(w)
+
+	var lastEventID uint64
+	if header := r.Header.Get(HeaderLastEventID); header != "" {
+		var err error
+		lastEventID, err = strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			m := NewMessage()
+			m.SetError(MapError(HeaderLastEventID, "should be event id"), Code(http.StatusBadRequest))
+			return m.Return(w)
+		}
+	}
+
+	sub, err := a.hub.Subscribe(topic, lastEventID)
+	if err != nil {
+		m := NewMessage()
+		m.SetError(Error("stream closed"), Code(http.StatusServiceUnavailable))
+		return m.Return(w)
+	}
+	defer sub.Close()
+
+	// stream outlives http.Server WriteTimeout and ReadTimeout
+	if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("reset write deadline: %w", err)
+	}
+
+	if err = rc.SetReadDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("reset read deadline: %w", err)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// disable nginx buffering
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err = rc.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	heartbeat := a.conf.HTTP.SSE.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = SSEHeartbeatDefault
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				// hub closed or client too slow, client reconnects with Last-Event-ID
+				return nil
+			}
+
+			if _, err = w.Write(encodeEvent(event)); err != nil {
+				return fmt.Errorf("write event: %w", err)
+			}
+
+		case <-ticker.C:
+			if _, err = w.Write([]byte(": ping\n\n")); err != nil {
+				return fmt.Errorf("write heartbeat: %w", err)
+			}
+		}
+
+		if err = rc.Flush(); err != nil {
+			return fmt.Errorf("flush: %w", err)
+		}
+	}
+}
+
+// encodeEvent text/event-stream format, multiline data is split to data fields
+func encodeEvent(event hub.Event) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("id: ")
+	buf.WriteString(strconv.FormatUint(event.ID, 10))
+	buf.WriteByte('\n')
+
+	if event.Name != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(event.Name)
+		buf.WriteByte('\n')
+	}
+
+	data := bytes.ReplaceAll(event.Data, []byte("\r\n"), []byte("\n"))
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteByte('\n')
+
+	return buf.Bytes()
+}
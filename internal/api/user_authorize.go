@@ -32,7 +32,7 @@ func (a *API) UserAuthorize(w http.ResponseWriter, r *http.Request, p httprouter
 	req := &UserAuthorizeReq{}
 	err := ReadRequest(r.Body, req)
 	if err != nil {
-		message.SetError(Error(err.Error()), Code(http.StatusBadRequest))
+		message.SetError(Error(err.Error()), Code(ReadRequestCode(err)))
 		_ = message.Return(w)
 		return
 	}
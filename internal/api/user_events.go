@@ -0,0 +1,20 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/andrdru/go-template/internal/ctxsess"
+	"github.com/andrdru/go-template/internal/managers"
+	"github.com/julienschmidt/httprouter"
+)
+
+// UserEvents stream of current user notifications
+func (a *API) UserEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sd := ctxsess.Get(r.Context())
+
+	err := a.Stream(w, r, managers.TopicUser(sd.UserID))
+	if err != nil {
+		a.logger.Info("user events stream", slog.Any("error", err))
+	}
+}
@@ -20,5 +20,5 @@ func (a *API) UserGet(w http.ResponseWriter, r *http.Request, p httprouter.Param
 
 	m.Data = UserGetResp{ID: sd.UserID}
 
-	_ = m.Return(w)
+	_ = m.ReturnCached(w, r, BodyETag())
 }
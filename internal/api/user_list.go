@@ -0,0 +1,72 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/andrdru/go-template/internal/pagination"
+	"github.com/julienschmidt/httprouter"
+)
+
+type (
+	UserListItem struct {
+		ID        int64     `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		Email     string    `json:"email"`
+		IsAdmin   bool      `json:"is_admin"`
+	}
+)
+
+var userListSpec = pagination.Spec{
+	DefaultLimit: 20,
+	MaxLimit:     100,
+	DefaultSort:  "-created_at",
+	SortFields:   []string{"id", "created_at", "email"},
+	Filters: map[string]pagination.FilterSpec{
+		"email": {
+			Type: pagination.FilterString,
+			Ops:  []pagination.Op{pagination.OpEq, pagination.OpIn},
+		},
+		"created_at": {
+			Type: pagination.FilterTime,
+			Ops:  []pagination.Op{pagination.OpGt, pagination.OpGte, pagination.OpLt, pagination.OpLte},
+		},
+	},
+}
+
+func (a *API) UserList(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	message := NewMessage()
+
+	params, ok := a.readListParams(r, userListSpec, message)
+	if !ok {
+		_ = message.Return(w)
+		return
+	}
+
+	users, err := a.userManager.List(r.Context(), params)
+	if err != nil {
+		a.logger.Error("list users", slog.Any("error", err))
+
+		message.SetError(OptInternalError)
+		_ = message.Return(w)
+		return
+	}
+
+	items := make([]UserListItem, 0, len(users))
+	for _, user := range users {
+		items = append(items, UserListItem{
+			ID:        user.ID,
+			CreatedAt: user.CreatedAt,
+			Email:     user.Email,
+			IsAdmin:   user.IsAdmin,
+		})
+	}
+
+	message.Data = NewListResponse(items, params.Limit, func(last UserListItem) string {
+		// items are built from users in same order
+		return a.paginator.Next(params, users[params.Limit-1].SortValue(params.Sort), last.ID)
+	})
+
+	_ = message.Return(w)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/andrdru/go-template/internal/ctxsess"
+	"github.com/andrdru/go-template/internal/hub"
+	"github.com/andrdru/go-template/internal/managers"
+	"github.com/andrdru/go-template/internal/ws"
+	"github.com/julienschmidt/httprouter"
+)
+
+// wsEvent user event pushed to websocket
+type wsEvent struct {
+	ID    uint64          `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// UserWebSocket realtime connection of current user, user topic events are pushed as json messages
+func (a *API) UserWebSocket(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	sd := ctxsess.Get(r.Context())
+
+	sub, err := a.hub.Subscribe(managers.TopicUser(sd.UserID), 0)
+	if err != nil {
+		m := NewMessage()
+		m.SetError(Error("server is shutting down"), Code(http.StatusServiceUnavailable))
+		_ = m.Return(w)
+		return
+	}
+
+	conn, err := a.ws.Serve(w, r, sd.UserID)
+	if err != nil {
+		sub.Close()
+
+		if errors.Is(err, ws.ErrClosed) {
+			m := NewMessage()
+			m.SetError(Error("server is shutting down"), Code(http.StatusServiceUnavailable))
+			_ = m.Return(w)
+			return
+		}
+
+		// upgrade error is already responded
+		a.logger.Info("websocket upgrade", slog.Any("error", err))
+		return
+	}
+
+	go a.forwardEvents(conn, sub)
+}
+
+// forwardEvents until connection is dropped, connection is closed when hub subscription ends
+func (a *API) forwardEvents(conn *ws.Conn, sub *hub.Subscription) {
+	defer sub.Close()
+
+	for {
+		select {
+		case <-conn.Done():
+			return
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				// hub closed or client too slow
+				conn.Close()
+				return
+			}
+
+			data, err := json.Marshal(wsEvent{ID: event.ID, Event: event.Name, Data: event.Data})
+			if err != nil {
+				a.logger.Error("websocket event marshal", slog.Any("error", err))
+				continue
+			}
+
+			if !conn.Send(data) {
+				return
+			}
+		}
+	}
+}
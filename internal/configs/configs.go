@@ -1,8 +1,13 @@
 package configs
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/andrdru/go-template/configs"
 	"gopkg.in/yaml.v3"
@@ -14,11 +19,264 @@ type (
 		IsDebug  bool             `yaml:"is_debug"`
 		Postgres configs.Postgres `yaml:"postgres"`
 		HTTP     HTTP             `yaml:"http"`
+		Redis    Redis            `yaml:"redis"`
+		GRPC     GRPC             `yaml:"grpc"`
+		Health   Health           `yaml:"health"`
+		Admin    Admin            `yaml:"admin"`
+		Outbox   Outbox           `yaml:"outbox"`
+		Jobs     Jobs             `yaml:"jobs"`
 	}
 
 	HTTP struct {
 		Host string `yaml:"host"`
 		Port string `yaml:"port"`
+		// http.Server timeouts, zero means no timeout
+		ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+		ReadTimeout       time.Duration `yaml:"read_timeout"`
+		WriteTimeout      time.Duration `yaml:"write_timeout"`
+		IdleTimeout       time.Duration `yaml:"idle_timeout"`
+		// AccessLogSampling share of logged requests in [0, 1]
+		AccessLogSampling float64         `yaml:"access_log_sampling"`
+		CORS              CORS            `yaml:"cors"`
+		SecurityHeaders   SecurityHeaders `yaml:"security_headers"`
+		Cookie            Cookie          `yaml:"cookie"`
+		CSRF              CSRF            `yaml:"csrf"`
+		RateLimit         RateLimit       `yaml:"rate_limit"`
+		Idempotency       Idempotency     `yaml:"idempotency"`
+		// CacheControl Cache-Control policies by "METHOD /route/:template"
+		CacheControl map[string]string `yaml:"cache_control"`
+		Compression  Compression       `yaml:"compression"`
+		BodyLimit    BodyLimit         `yaml:"body_limit"`
+		Timeout      Timeout           `yaml:"timeout"`
+		Pagination   Pagination        `yaml:"pagination"`
+		SSE          SSE               `yaml:"sse"`
+		WebSocket    WebSocket         `yaml:"websocket"`
+	}
+
+	// CORS cross-origin requests config
+	// empty AllowedOrigins disables CORS, "*" can't be combined with AllowCredentials
+	CORS struct {
+		// AllowedOrigins list of origins, "*" allows any
+		AllowedOrigins []string `yaml:"allowed_origins"`
+		// AllowedMethods empty list allows methods of matched route
+		AllowedMethods []string `yaml:"allowed_methods"`
+		// AllowedHeaders empty list allows middlewares.CORSAllowedHeadersDefault
+		AllowedHeaders   []string `yaml:"allowed_headers"`
+		ExposedHeaders   []string `yaml:"exposed_headers"`
+		AllowCredentials bool     `yaml:"allow_credentials"`
+		// MaxAge preflight cache duration, seconds
+		MaxAge int `yaml:"max_age"`
+	}
+
+	// SecurityHeaders response security headers config
+	// empty values are not sent
+	SecurityHeaders struct {
+		// HSTSMaxAge Strict-Transport-Security max-age, seconds
+		HSTSMaxAge            int    `yaml:"hsts_max_age"`
+		HSTSIncludeSubdomains bool   `yaml:"hsts_include_subdomains"`
+		FrameOptions          string `yaml:"frame_options"`
+		ContentSecurityPolicy string `yaml:"content_security_policy"`
+		ReferrerPolicy        string `yaml:"referrer_policy"`
+	}
+
+	// Cookie attributes of cookies set by app
+	Cookie struct {
+		Secure bool `yaml:"secure"`
+		// SameSite one of: lax, strict, none. Empty is browser default
+		SameSite string `yaml:"same_site"`
+	}
+
+	// CSRF double-submit cookie protection config
+	CSRF struct {
+		Enabled    bool   `yaml:"enabled"`
+		CookieName string `yaml:"cookie_name"`
+		HeaderName string `yaml:"header_name"`
+	}
+
+	// RateLimit token bucket rate limiting config
+	RateLimit struct {
+		Enabled bool `yaml:"enabled"`
+		// Storage one of: memory, redis
+		Storage string        `yaml:"storage"`
+		Default RateLimitRule `yaml:"default"`
+		// Routes rules by "METHOD /route/:template", override Default
+		Routes map[string]RateLimitRule `yaml:"routes"`
+		// TrustedProxies IPs and CIDRs of proxies setting X-Real-IP, empty trusts none
+		TrustedProxies []string `yaml:"trusted_proxies"`
+		// APIKeys keys of internal consumers sent as X-API-Key header,
+		// limit by api_key applies to them only, other requests are limited by ip
+		APIKeys []string `yaml:"api_keys"`
+	}
+
+	// RateLimitRule Requests per Period, up to Burst at once
+	// zero Requests disables limit
+	RateLimitRule struct {
+		Requests int           `yaml:"requests"`
+		Period   time.Duration `yaml:"period"`
+		Burst    int           `yaml:"burst"`
+		// Key one of: ip, user, api_key
+		Key string `yaml:"key"`
+	}
+
+	// Idempotency Idempotency-Key support for POST routes
+	Idempotency struct {
+		Enabled bool `yaml:"enabled"`
+		// LockTimeout time to process request before duplicate may take over
+		LockTimeout time.Duration `yaml:"lock_timeout"`
+		// TTL how long response is replayed
+		TTL time.Duration `yaml:"ttl"`
+	}
+
+	// Compression response compression config, empty values use defaults
+	Compression struct {
+		Enabled bool `yaml:"enabled"`
+		// MinSize smaller responses are sent as is, bytes
+		MinSize      int      `yaml:"min_size"`
+		ContentTypes []string `yaml:"content_types"`
+		// Encodings server preference order of: zstd, br, gzip
+		Encodings []string `yaml:"encodings"`
+		// ExcludePaths path prefixes never compressed
+		ExcludePaths []string `yaml:"exclude_paths"`
+	}
+
+	// BodyLimit request body size limit, bytes
+	// zero Default uses middlewares.BodyLimitDefault
+	BodyLimit struct {
+		Default int64 `yaml:"default"`
+		// Routes limits by "METHOD /route/:template", override Default
+		Routes map[string]int64 `yaml:"routes"`
+	}
+
+	// Timeout handler deadline, zero disables
+	Timeout struct {
+		Default time.Duration `yaml:"default"`
+		// Routes timeouts by "METHOD /route/:template", override Default
+		Routes map[string]time.Duration `yaml:"routes"`
+	}
+
+	Pagination struct {
+		// CursorSecret list cursors signing key
+		CursorSecret string `yaml:"cursor_secret"`
+	}
+
+	// SSE Server-Sent Events streams config, empty values use defaults
+	SSE struct {
+		// Heartbeat comment interval keeping idle stream alive
+		Heartbeat time.Duration `yaml:"heartbeat"`
+		// History events kept per topic to resume stream
+		History int `yaml:"history"`
+		// Buffer events queued per stream before slow client is dropped
+		Buffer int `yaml:"buffer"`
+	}
+
+	// WebSocket connections config, empty values use defaults
+	// Origin is checked against CORS AllowedOrigins
+	WebSocket struct {
+		PingPeriod time.Duration `yaml:"ping_period"`
+		// PongTimeout connection without pong is dropped, should exceed PingPeriod
+		PongTimeout  time.Duration `yaml:"pong_timeout"`
+		WriteTimeout time.Duration `yaml:"write_timeout"`
+		// SendBuffer messages queued per connection before slow client is dropped
+		SendBuffer int `yaml:"send_buffer"`
+		// MaxMessageSize client message size limit, bytes
+		MaxMessageSize int64 `yaml:"max_message_size"`
+	}
+
+	// GRPC optional gRPC API listener
+	GRPC struct {
+		Enabled bool   `yaml:"enabled"`
+		Host    string `yaml:"host"`
+		Port    string `yaml:"port"`
+		// APIKeys keys of internal consumers, sent as "x-api-key" metadata
+		APIKeys    []string `yaml:"api_keys"`
+		Reflection bool     `yaml:"reflection"`
+		// RateLimit rules by full method "/template.v1.AuthService/Login",
+		// buckets are kept by http rate limit storage
+		RateLimit map[string]RateLimitRule `yaml:"rate_limit"`
+	}
+
+	// Health readiness checks config, empty values use defaults
+	Health struct {
+		// Timeout single check deadline
+		Timeout time.Duration `yaml:"timeout"`
+		// CacheTTL check result is reused
+		CacheTTL time.Duration `yaml:"cache_ttl"`
+		// ShutdownDelay readiness fails this long before servers stop,
+		// so load balancer stops routing to pod
+		ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	}
+
+	// Admin operational listener: health, metrics, pprof
+	Admin struct {
+		Host      string    `yaml:"host"`
+		Port      string    `yaml:"port"`
+		BasicAuth BasicAuth `yaml:"basic_auth"`
+		// AllowedIPs IPs and CIDRs, empty allows any. Health is not restricted
+		AllowedIPs []string `yaml:"allowed_ips"`
+	}
+
+	// BasicAuth empty Username disables
+	BasicAuth struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	}
+
+	// Outbox domain events relay, empty values use defaults
+	Outbox struct {
+		Enabled bool `yaml:"enabled"`
+		// Publisher one of: log, webhook, redis
+		Publisher string `yaml:"publisher"`
+		// PollInterval pause when no events are due
+		PollInterval time.Duration `yaml:"poll_interval"`
+		BatchSize    int           `yaml:"batch_size"`
+		// MaxAttempts event goes to dead letters after this many failed deliveries
+		MaxAttempts    int           `yaml:"max_attempts"`
+		BackoffBase    time.Duration `yaml:"backoff_base"`
+		BackoffMax     time.Duration `yaml:"backoff_max"`
+		PublishTimeout time.Duration `yaml:"publish_timeout"`
+		// Lease claimed batch delivery deadline, rest of batch is claimed again after
+		Lease       time.Duration `yaml:"lease"`
+		Webhook     OutboxWebhook `yaml:"webhook"`
+		RedisStream RedisStream   `yaml:"redis_stream"`
+	}
+
+	OutboxWebhook struct {
+		URL string `yaml:"url"`
+		// Secret HMAC key of body signature, empty disables signing
+		Secret string `yaml:"secret"`
+	}
+
+	// RedisStream stream name is Prefix and event topic
+	RedisStream struct {
+		Prefix string `yaml:"prefix"`
+		// MaxLen approximate stream length limit, zero keeps all entries
+		MaxLen int64 `yaml:"max_len"`
+	}
+
+	// Jobs background jobs worker, empty values use defaults
+	Jobs struct {
+		// Enabled run worker in app, "-script worker" runs it alone
+		Enabled     bool `yaml:"enabled"`
+		Concurrency int  `yaml:"concurrency"`
+		// PollInterval pause when no jobs are due
+		PollInterval time.Duration `yaml:"poll_interval"`
+		// VisibilityTimeout job run deadline, job is taken by other worker after
+		VisibilityTimeout time.Duration `yaml:"visibility_timeout"`
+		BackoffBase       time.Duration `yaml:"backoff_base"`
+		BackoffMax        time.Duration `yaml:"backoff_max"`
+		// ScheduleInterval periodic jobs are enqueued by leader replica, zero disables
+		ScheduleInterval time.Duration `yaml:"schedule_interval"`
+		// SessionsRetention logged out sessions are purged after, zero disables
+		SessionsRetention time.Duration `yaml:"sessions_retention"`
+		// JobsRetention finished jobs are purged after, zero disables
+		JobsRetention time.Duration `yaml:"jobs_retention"`
+		// OutboxRetention delivered and dead outbox events are purged after, zero disables
+		OutboxRetention time.Duration `yaml:"outbox_retention"`
+	}
+
+	Redis struct {
+		Address string        `yaml:"address"`
+		Timeout time.Duration `yaml:"timeout"`
 	}
 )
 
@@ -34,5 +292,37 @@ func NewConfig(path string) (config Config, err error) {
 		return config, fmt.Errorf("could not unmarshal config: %w", err)
 	}
 
+	if err = config.Validate(); err != nil {
+		return config, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return config, nil
 }
+
+// Validate reject unsafe values
+func (c Config) Validate() error {
+	if c.HTTP.CORS.AllowCredentials && slices.Contains(c.HTTP.CORS.AllowedOrigins, "*") {
+		return errors.New(`http.cors: allowed origin "*" with allow_credentials`)
+	}
+
+	// cursors signed with empty key are forged by anyone
+	if c.HTTP.Pagination.CursorSecret == "" {
+		return errors.New("http.pagination: empty cursor_secret")
+	}
+
+	return nil
+}
+
+// SameSiteMode http.SameSite by config value
+func (c Cookie) SameSiteMode() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
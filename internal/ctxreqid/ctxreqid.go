@@ -0,0 +1,30 @@
+package ctxreqid
+
+import (
+	"context"
+)
+
+type (
+	ctxKey string
+)
+
+const (
+	keyRequestID ctxKey = "key"
+)
+
+// Set request id to context
+func Set(parent context.Context, requestID string) context.Context {
+	return context.WithValue(parent, keyRequestID, requestID)
+}
+
+// Get request id from context
+func Get(ctx context.Context) string {
+	data := ctx.Value(keyRequestID)
+	if data != nil {
+		if ret, ok := data.(string); ok {
+			return ret
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Idempotency struct {
+	ID          int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Key         string
+	Fingerprint string
+	LockedUntil *time.Time
+
+	// Response nil while request in progress
+	Response *IdempotencyResponse
+}
+
+// IdempotencyResponse saved response to replay
+type IdempotencyResponse struct {
+	Status  int
+	Headers IdempotencyHeaders
+	Body    []byte
+}
+
+type IdempotencyHeaders map[string][]string
+
+// Scan implement sql.Scanner
+func (h *IdempotencyHeaders) Scan(src interface{}) (err error) {
+	var source []byte
+	switch v := src.(type) {
+	case []byte:
+		source = v
+	case nil:
+		*h = nil
+		return nil
+	default:
+		return ErrUnknownFieldDataType
+	}
+
+	err = json.Unmarshal(source, h)
+	if err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+// Value implement sql/driver.Valuer
+func (h IdempotencyHeaders) Value() (driver.Value, error) {
+	return json.Marshal(h)
+}
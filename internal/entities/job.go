@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"time"
+)
+
+const (
+	JobStatusPending = "pending"
+	JobStatusDone    = "done"
+	// JobStatusFailed attempts exhausted or job is not retryable
+	JobStatusFailed = "failed"
+)
+
+// Job background job
+type Job struct {
+	ID        int64
+	CreatedAt time.Time
+	// Name handler name
+	Name string
+	// Payload json
+	Payload []byte
+	// UniqueKey optional, see jobs.Unique
+	UniqueKey *string
+	// Attempts started attempts, current one included
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+}
@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"time"
+)
+
+// OutboxEvent domain event delivered after transaction commit
+type OutboxEvent struct {
+	ID        int64
+	CreatedAt time.Time
+	// Topic destination, e.g. redis stream
+	Topic string
+	// Name event type, e.g. user.registered
+	Name string
+	// Key entity key for consumers, optional
+	Key string
+	// Payload json
+	Payload []byte
+	// Attempts failed delivery attempts
+	Attempts int
+}
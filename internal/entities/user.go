@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"strconv"
 	"time"
 )
 
@@ -11,6 +12,19 @@ type User struct {
 	DeletedAt *time.Time
 	Email     string
 	Passhash  string
+	IsAdmin   bool
 
 	Sessions []Session
 }
+
+// SortValue cursor value of list sort field, id by default
+func (u User) SortValue(field string) string {
+	switch field {
+	case "created_at":
+		return u.CreatedAt.Format(time.RFC3339Nano)
+	case "email":
+		return u.Email
+	default:
+		return strconv.FormatInt(u.ID, 10)
+	}
+}
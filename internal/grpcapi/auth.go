@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/grpcapi/pb"
+)
+
+type (
+	authServer struct {
+		pb.UnimplementedAuthServiceServer
+		*Server
+	}
+)
+
+func (s *authServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	if req.GetEmail() == "" || req.GetPass() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and pass should not be empty")
+	}
+
+	session := entities.Session{
+		Extra: entities.SessionExtra{
+			UserAgent: req.GetUserAgent(),
+		},
+		Email: req.GetEmail(),
+		Pass:  req.GetPass(),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		session.Extra.IP = p.Addr.String()
+	}
+
+	session, err := s.authManager.CreateSession(ctx, session)
+	if err != nil {
+		if errors.Is(err, entities.ErrNotFound) || errors.Is(err, entities.ErrNotAllowed) {
+			return nil, status.Error(codes.Unauthenticated, "invalid email or pass")
+		}
+
+		s.logger.Error("login", slog.Any("error", err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.LoginResponse{
+		UserId: session.UserID,
+		Token:  session.Token,
+	}, nil
+}
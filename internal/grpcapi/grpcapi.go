@@ -0,0 +1,131 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/grpcapi/pb"
+	"github.com/andrdru/go-template/internal/pagination"
+	"github.com/andrdru/go-template/internal/ratelimit"
+)
+
+type (
+	// Server gRPC API, shares managers with http API
+	Server struct {
+		logger *slog.Logger
+		server *grpc.Server
+		health *health.Server
+
+		authManager authManager
+		userManager userManager
+		paginator   *pagination.Paginator
+		rateLimiter rateLimiter
+		// apiKeys sha256 of configured keys
+		apiKeys [][]byte
+		// rateLimits rules by full method
+		rateLimits map[string]configs.RateLimitRule
+	}
+
+	authManager interface {
+		CheckToken(ctx context.Context, token string) (context.Context, error)
+		CreateSession(ctx context.Context, session entities.Session) (entities.Session, error)
+	}
+
+	userManager interface {
+		List(ctx context.Context, params pagination.Params) ([]entities.User, error)
+	}
+
+	rateLimiter interface {
+		Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+	}
+)
+
+// NewServer register services, health and optional reflection
+// rateLimiter is shared with http API
+func NewServer(
+	logger *slog.Logger,
+	conf configs.GRPC,
+	paginator *pagination.Paginator,
+	authManager authManager,
+	userManager userManager,
+	rateLimiter rateLimiter,
+) *Server {
+	s := &Server{
+		logger:      logger,
+		health:      health.NewServer(),
+		authManager: authManager,
+		userManager: userManager,
+		paginator:   paginator,
+		rateLimiter: rateLimiter,
+		rateLimits:  conf.RateLimit,
+	}
+
+	for _, key := range conf.APIKeys {
+		sum := sha256.Sum256([]byte(key))
+		s.apiKeys = append(s.apiKeys, sum[:])
+	}
+
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryObserve, s.unaryRecover, s.unaryAuth, s.unaryRateLimit),
+		grpc.ChainStreamInterceptor(s.streamObserve, s.streamRecover),
+	)
+
+	pb.RegisterAuthServiceServer(s.server, &authServer{Server: s})
+	pb.RegisterUserServiceServer(s.server, &userServer{Server: s})
+	grpc_health_v1.RegisterHealthServer(s.server, s.health)
+
+	if conf.Reflection {
+		reflection.Register(s.server)
+	}
+
+	return s
+}
+
+// Serve blocks until Stop
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Stop report not serving to health checks, wait for running calls until ctx is done
+func (s *Server) Stop(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return fmt.Errorf("graceful stop: %w", ctx.Err())
+	}
+}
+
+func (s *Server) validAPIKey(key string) bool {
+	sum := sha256.Sum256([]byte(key))
+
+	valid := false
+	for _, apiKey := range s.apiKeys {
+		// check all keys, constant time
+		if subtle.ConstantTimeCompare(sum[:], apiKey) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
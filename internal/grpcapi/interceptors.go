@@ -0,0 +1,262 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/andrdru/go-template/internal/ctxsess"
+	"github.com/andrdru/go-template/internal/grpcapi/pb"
+	"github.com/andrdru/go-template/internal/metrics"
+	"github.com/andrdru/go-template/internal/middlewares"
+	"github.com/andrdru/go-template/internal/ratelimit"
+)
+
+type (
+	ctxKey string
+)
+
+const (
+	// MetadataAuthorization "Bearer <session token>"
+	MetadataAuthorization = "authorization"
+	// MetadataAPIKey internal consumer key
+	MetadataAPIKey = "x-api-key"
+	// MetadataRetryAfter seconds until rate limited call may be retried
+	MetadataRetryAfter = "retry-after"
+
+	keyAPIKey ctxKey = "api_key"
+)
+
+// unaryObserve metrics and log line per call
+func (s *Server) unaryObserve(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	s.observe(ctx, info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// streamObserve metrics and log line per stream
+func (s *Server) streamObserve(
+	srv any,
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+
+	err := handler(srv, ss)
+
+	s.observe(ss.Context(), info.FullMethod, start, err)
+
+	return err
+}
+
+func (s *Server) observe(ctx context.Context, method string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+
+	metrics.ObserveGRPC(method, code.String(), duration.Seconds())
+
+	attrs := []any{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", duration),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("remote_addr", p.Addr.String()))
+	}
+
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		s.logger.Error("grpc request", append(attrs, slog.Any("error", err))...)
+	default:
+		s.logger.Info("grpc request", attrs...)
+	}
+}
+
+// unaryRecover respond Internal on panic
+func (s *Server) unaryRecover(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = s.recovered(info.FullMethod, rec)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// streamRecover respond Internal on panic
+func (s *Server) streamRecover(
+	srv any,
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = s.recovered(info.FullMethod, rec)
+		}
+	}()
+
+	return handler(srv, ss)
+}
+
+func (s *Server) recovered(method string, rec any) error {
+	metrics.CounterPanics("grpc").Inc()
+
+	s.logger.Error("panic recovered",
+		slog.String("method", method),
+		slog.Any("error", rec),
+		slog.String("stack", string(debug.Stack())),
+	)
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// unaryAuth require session token or api key for UserService
+func (s *Server) unaryAuth(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+pb.UserService_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if key := first(md, MetadataAPIKey); key != "" {
+		if !s.validAPIKey(key) {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+
+		return handler(context.WithValue(ctx, keyAPIKey, true), req)
+	}
+
+	token, ok := strings.CutPrefix(first(md, MetadataAuthorization), "Bearer ")
+	if !ok || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "session token or api key required")
+	}
+
+	ctx, err := s.authManager.CheckToken(ctx, token)
+	if err != nil {
+		if !errors.Is(err, middlewares.ErrNotAllowed) {
+			s.logger.Error("session validate", slog.Any("error", err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		return nil, status.Error(codes.Unauthenticated, "invalid session token")
+	}
+
+	return handler(ctx, req)
+}
+
+// unaryRateLimit limit calls of methods with configured rules by token bucket
+// key user requires unaryAuth before, falls back to ip.
+// limiter errors are logged and call passes
+func (s *Server) unaryRateLimit(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	rule, ok := s.rateLimits[info.FullMethod]
+	if !ok || rule.Requests <= 0 || rule.Period <= 0 {
+		return handler(ctx, req)
+	}
+
+	limit := ratelimit.Limit{
+		Requests: rule.Requests,
+		Period:   rule.Period,
+		Burst:    rule.Burst,
+	}
+
+	res, err := s.rateLimiter.Take(ctx, info.FullMethod+":"+rateLimitKey(ctx, rule.Key), limit)
+	if err != nil {
+		metrics.CounterRateLimit(info.FullMethod, "error").Inc()
+		s.logger.Error("rate limit", slog.Any("error", err))
+
+		return handler(ctx, req)
+	}
+
+	if !res.Allowed {
+		metrics.CounterRateLimit(info.FullMethod, "limited").Inc()
+
+		retryAfter := (res.RetryAfter + time.Second - 1) / time.Second
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataRetryAfter, strconv.Itoa(int(retryAfter))))
+
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	}
+
+	metrics.CounterRateLimit(info.FullMethod, "allowed").Inc()
+
+	return handler(ctx, req)
+}
+
+func rateLimitKey(ctx context.Context, keyType string) string {
+	switch keyType {
+	case "user":
+		if sess := ctxsess.Get(ctx); sess != nil {
+			return "user:" + strconv.FormatInt(sess.UserID, 10)
+		}
+	case "api_key":
+		md, _ := metadata.FromIncomingContext(ctx)
+		if apiKey := first(md, MetadataAPIKey); apiKey != "" {
+			// do not store raw keys in limiter storage
+			sum := sha256.Sum256([]byte(apiKey))
+			return "api_key:" + hex.EncodeToString(sum[:])
+		}
+	}
+
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	return "ip:" + ip
+}
+
+// isAPIKey call authorized by api key
+func isAPIKey(ctx context.Context) bool {
+	ok, _ := ctx.Value(keyAPIKey).(bool)
+	return ok
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
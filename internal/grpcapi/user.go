@@ -0,0 +1,94 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/andrdru/go-template/internal/ctxsess"
+	"github.com/andrdru/go-template/internal/grpcapi/pb"
+	"github.com/andrdru/go-template/internal/pagination"
+)
+
+type (
+	userServer struct {
+		pb.UnimplementedUserServiceServer
+		*Server
+	}
+)
+
+var userListSpec = pagination.Spec{
+	DefaultLimit: 20,
+	MaxLimit:     100,
+	DefaultSort:  "-created_at",
+	SortFields:   []string{"id", "created_at", "email"},
+}
+
+func (s *userServer) GetMe(ctx context.Context, _ *pb.GetMeRequest) (*pb.GetMeResponse, error) {
+	sd := ctxsess.Get(ctx)
+	if sd == nil {
+		return nil, status.Error(codes.Unauthenticated, "session token required")
+	}
+
+	return &pb.GetMeResponse{Id: sd.UserID}, nil
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	sd := ctxsess.Get(ctx)
+	if !isAPIKey(ctx) && (sd == nil || sd.User == nil || !sd.User.IsAdmin) {
+		return nil, status.Error(codes.PermissionDenied, "admin only")
+	}
+
+	query := url.Values{}
+	if req.GetPageSize() > 0 {
+		query.Set("limit", strconv.Itoa(int(req.GetPageSize())))
+	}
+	if req.GetPageToken() != "" {
+		query.Set("cursor", req.GetPageToken())
+	}
+	if req.GetSort() != "" {
+		query.Set("sort", req.GetSort())
+	}
+
+	params, err := s.paginator.Parse(query, userListSpec)
+	if err != nil {
+		var fieldErr *pagination.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %s", fieldErr.Field, fieldErr.Message)
+		}
+
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	users, err := s.userManager.List(ctx, params)
+	if err != nil {
+		s.logger.Error("list users", slog.Any("error", err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &pb.ListUsersResponse{}
+
+	// one extra user is fetched if next page exists
+	if len(users) > params.Limit {
+		users = users[:params.Limit]
+		last := users[len(users)-1]
+		resp.NextPageToken = s.paginator.Next(params, last.SortValue(params.Sort), last.ID)
+	}
+
+	for _, user := range users {
+		resp.Users = append(resp.Users, &pb.User{
+			Id:        user.ID,
+			CreatedAt: timestamppb.New(user.CreatedAt),
+			Email:     user.Email,
+			IsAdmin:   user.IsAdmin,
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,220 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// CheckFunc returns nil if dependency is healthy
+	CheckFunc func(ctx context.Context) error
+
+	// Registry named readiness checks
+	Registry struct {
+		mu     sync.RWMutex
+		checks []*check
+		// shutdown readiness fails once shutdown begins
+		shutdown atomic.Bool
+
+		defaults options
+	}
+
+	check struct {
+		name string
+		fn   CheckFunc
+		options
+
+		// mu one probe at a time, concurrent requests share its result
+		mu        sync.Mutex
+		result    Result
+		checkedAt time.Time
+	}
+
+	// Report readiness breakdown
+	Report struct {
+		Status string            `json:"status"`
+		Checks map[string]Result `json:"checks,omitempty"`
+	}
+
+	// Result of single check
+	Result struct {
+		Status    string    `json:"status"`
+		Error     string    `json:"error,omitempty"`
+		Duration  float64   `json:"duration_seconds"`
+		CheckedAt time.Time `json:"checked_at"`
+	}
+
+	options struct {
+		timeout  time.Duration
+		cacheTTL time.Duration
+	}
+
+	Option func(*options)
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+var (
+	// TimeoutDefault single check timeout
+	TimeoutDefault = time.Second
+	// CacheTTLDefault check result is reused, probes do not hammer dependencies
+	CacheTTLDefault = time.Second
+)
+
+// NewRegistry options are defaults of registered checks
+func NewRegistry(opts ...Option) *Registry {
+	args := options{
+		timeout:  TimeoutDefault,
+		cacheTTL: CacheTTLDefault,
+	}
+
+	for _, opt := range opts {
+		opt(&args)
+	}
+
+	return &Registry{
+		defaults: args,
+	}
+}
+
+// Register readiness check, options override registry defaults
+func (r *Registry) Register(name string, fn CheckFunc, opts ...Option) {
+	c := &check{
+		name:    name,
+		fn:      fn,
+		options: r.defaults,
+	}
+
+	for _, opt := range opts {
+		opt(&c.options)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, c)
+}
+
+// Shutdown readiness fails from now on, call before stopping servers
+func (r *Registry) Shutdown() {
+	r.shutdown.Store(true)
+}
+
+// Ready run checks concurrently, cached results are reused
+func (r *Registry) Ready(ctx context.Context) Report {
+	if r.shutdown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(checks)),
+	}
+
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+// LiveHandler process is up, dependencies are not checked
+func (r *Registry) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// ReadyHandler 200 if all checks pass, 503 otherwise
+func (r *Registry) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Ready(req.Context())
+
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeReport(w, code, report)
+	})
+}
+
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.cacheTTL {
+		return c.result
+	}
+
+	// result is shared, disconnect of one client should not fail it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+
+	c.checkedAt = time.Now()
+	c.result = Result{
+		Status:    StatusOK,
+		Duration:  c.checkedAt.Sub(start).Seconds(),
+		CheckedAt: c.checkedAt,
+	}
+
+	if err != nil {
+		c.result.Status = StatusFail
+		c.result.Error = err.Error()
+	}
+
+	return c.result
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	data, _ := json.Marshal(report)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// WithTimeout check deadline
+func WithTimeout(timeout time.Duration) Option {
+	return func(args *options) {
+		args.timeout = timeout
+	}
+}
+
+// WithCacheTTL how long check result is reused
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(args *options) {
+		args.cacheTTL = ttl
+	}
+}
@@ -0,0 +1,260 @@
+package hub
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type (
+	// Hub in-process pub/sub by topic
+	// keeps recent events of topic to resume subscription
+	Hub struct {
+		mu      sync.Mutex
+		topics  map[string]*topic
+		lastID  uint64
+		closed  bool
+		sweepAt time.Time
+
+		history   int
+		buffer    int
+		retention time.Duration
+	}
+
+	Event struct {
+		// ID increasing within hub, used as SSE id
+		ID    uint64
+		Topic string
+		Name  string
+		Data  []byte
+	}
+
+	// Subscription events channel is closed on Close, hub Close or slow reading
+	Subscription struct {
+		hub   *Hub
+		topic string
+		ch    chan Event
+	}
+
+	topic struct {
+		history []Event
+		subs    map[*Subscription]struct{}
+		// idleSince last subscriber left
+		idleSince time.Time
+	}
+
+	options struct {
+		history   int
+		buffer    int
+		retention time.Duration
+	}
+
+	Option func(*options)
+)
+
+var (
+	// HistoryDefault events kept per topic
+	HistoryDefault = 100
+	// BufferDefault subscription channel size
+	BufferDefault = 64
+	// RetentionDefault topic without subscribers is kept to resume
+	RetentionDefault = 5 * time.Minute
+
+	ErrClosed = errors.New("hub closed")
+)
+
+// NewHub .
+func NewHub(opts ...Option) *Hub {
+	args := &options{
+		history:   HistoryDefault,
+		buffer:    BufferDefault,
+		retention: RetentionDefault,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	return &Hub{
+		topics: make(map[string]*topic),
+		// ids keep increasing after restart, client Last-Event-ID stays comparable
+		lastID:    uint64(time.Now().UnixNano()),
+		sweepAt:   time.Now().Add(args.retention),
+		history:   args.history,
+		buffer:    args.buffer,
+		retention: args.retention,
+	}
+}
+
+// Publish event to topic subscribers
+// topic without recent subscribers is skipped, nobody may resume it.
+// slow subscriber with full buffer is dropped, it may resume with last event id
+func (h *Hub) Publish(topicName string, name string, data []byte) (Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return Event{}, ErrClosed
+	}
+
+	h.lastID++
+	event := Event{
+		ID:    h.lastID,
+		Topic: topicName,
+		Name:  name,
+		Data:  data,
+	}
+
+	t, ok := h.topics[topicName]
+	if !ok {
+		return event, nil
+	}
+
+	t.history = append(t.history, event)
+	if len(t.history) > h.history {
+		t.history = t.history[len(t.history)-h.history:]
+	}
+
+	for sub := range t.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			h.unsubscribe(sub)
+		}
+	}
+
+	return event, nil
+}
+
+// Subscribe to topic, events after lastEventID are replayed from history
+// zero lastEventID means no replay
+func (h *Hub) Subscribe(topicName string, lastEventID uint64) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	h.sweep(time.Now())
+
+	t, ok := h.topics[topicName]
+	if !ok {
+		t = &topic{
+			subs: make(map[*Subscription]struct{}),
+		}
+		h.topics[topicName] = t
+	}
+
+	var replay []Event
+	if lastEventID > 0 {
+		for _, event := range t.history {
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		hub:   h,
+		topic: topicName,
+		ch:    make(chan Event, h.buffer+len(replay)),
+	}
+
+	for _, event := range replay {
+		sub.ch <- event
+	}
+
+	t.subs[sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Close all subscriptions, next Publish and Subscribe fail
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.closed = true
+
+	for _, t := range h.topics {
+		for sub := range t.subs {
+			close(sub.ch)
+		}
+	}
+
+	h.topics = nil
+}
+
+// Events channel, closed when subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close unsubscribe, safe to call many times
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.unsubscribe(s)
+}
+
+// sweep drop topics idle longer than retention, lock required
+func (h *Hub) sweep(now time.Time) {
+	if now.Before(h.sweepAt) {
+		return
+	}
+
+	for name, t := range h.topics {
+		if len(t.subs) == 0 && now.Sub(t.idleSince) > h.retention {
+			delete(h.topics, name)
+		}
+	}
+
+	h.sweepAt = now.Add(h.retention)
+}
+
+// unsubscribe lock required
+func (h *Hub) unsubscribe(sub *Subscription) {
+	t, ok := h.topics[sub.topic]
+	if !ok {
+		return
+	}
+
+	if _, ok = t.subs[sub]; !ok {
+		return
+	}
+
+	delete(t.subs, sub)
+	close(sub.ch)
+
+	// history is kept for resume until retention passes
+	if len(t.subs) == 0 {
+		t.idleSince = time.Now()
+	}
+}
+
+// WithHistory events kept per topic for resume
+func WithHistory(history int) Option {
+	return func(args *options) {
+		args.history = history
+	}
+}
+
+// WithBuffer subscription channel size
+func WithBuffer(buffer int) Option {
+	return func(args *options) {
+		args.buffer = buffer
+	}
+}
+
+// WithRetention how long topic without subscribers is kept to resume
+func WithRetention(retention time.Duration) Option {
+	return func(args *options) {
+		args.retention = retention
+	}
+}
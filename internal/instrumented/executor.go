@@ -0,0 +1,237 @@
+package instrumented
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/andrdru/go-template/internal/ctxreqid"
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/metrics"
+	"github.com/andrdru/go-template/tx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	// Executor query executor recording metrics, slow query logs and tracing spans
+	// query name is taken from "-- name: query_name" comment, see WithName otherwise.
+	// QueryContext is timed until rows are returned, rows reading is not included
+	Executor struct {
+		db   tx.QueryExecutor
+		args *options
+	}
+
+	options struct {
+		database      string
+		slowThreshold time.Duration
+		logger        *slog.Logger
+		tracer        trace.Tracer
+	}
+
+	Option func(*options)
+
+	ctxKey string
+)
+
+const (
+	keyName ctxKey = "name"
+
+	// NameUnknown query without name comment and context name
+	NameUnknown = "unknown"
+
+	commentName = "-- name:"
+)
+
+var (
+	// DatabaseDefault metrics database label
+	DatabaseDefault = "postgres"
+)
+
+var _ tx.QueryExecutor = &Executor{}
+
+// NewExecutor .
+func NewExecutor(db tx.QueryExecutor, opts ...Option) *Executor {
+	return &Executor{
+		db:   db,
+		args: newOptions(opts),
+	}
+}
+
+// Wrap executor decorator for tx.WithWrapper
+func Wrap(opts ...Option) func(db tx.QueryExecutor) tx.QueryExecutor {
+	args := newOptions(opts)
+
+	return func(db tx.QueryExecutor) tx.QueryExecutor {
+		return &Executor{
+			db:   db,
+			args: args,
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	args := &options{
+		database: DatabaseDefault,
+		logger:   slog.Default(),
+		tracer:   otel.Tracer("github.com/andrdru/go-template/internal/instrumented"),
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	return args
+}
+
+// ExecContext .
+func (e *Executor) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	ctx, done := e.start(ctx, "exec", query, args)
+	defer func() {
+		done(err)
+	}()
+
+	return e.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext .
+func (e *Executor) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	ctx, done := e.start(ctx, "query", query, args)
+	defer func() {
+		done(err)
+	}()
+
+	return e.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext query error is observed, sql.ErrNoRows of Scan is not
+func (e *Executor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := e.start(ctx, "query_row", query, args)
+
+	row := e.db.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+
+	return row
+}
+
+// start query span, done records result
+func (e *Executor) start(
+	ctx context.Context,
+	operation string,
+	query string,
+	args []interface{},
+) (spanCtx context.Context, done func(err error)) {
+	name := queryName(ctx, query)
+	start := time.Now()
+
+	spanCtx, span := e.args.tracer.Start(ctx, "sql "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", e.args.database),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", query),
+		),
+	)
+
+	return spanCtx, func(err error) {
+		d := time.Since(start)
+
+		metrics.HistogramObserverDB(e.args.database, name, entities.Err(err)).Observe(d.Seconds())
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		if e.args.slowThreshold > 0 && d >= e.args.slowThreshold {
+			e.args.logger.Warn("slow query",
+				slog.String("request_id", ctxreqid.Get(ctx)),
+				slog.String("database", e.args.database),
+				slog.String("name", name),
+				slog.Duration("duration", d),
+				slog.String("query", strings.Join(strings.Fields(query), " ")),
+				slog.Any("args", redact(args)),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// queryName by comment, context name otherwise
+func queryName(ctx context.Context, query string) string {
+	if i := strings.Index(query, commentName); i >= 0 {
+		line := query[i+len(commentName):]
+		if end := strings.IndexByte(line, '\n'); end >= 0 {
+			line = line[:end]
+		}
+
+		// sqlc style "-- name: CreateUser :one"
+		if fields := strings.Fields(line); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	if name, ok := ctx.Value(keyName).(string); ok && name != "" {
+		return name
+	}
+
+	return NameUnknown
+}
+
+// redact args values, types are kept to debug
+func redact(args []interface{}) []string {
+	ret := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+			ret = append(ret, "NULL")
+		case string:
+			ret = append(ret, fmt.Sprintf("string(%d)", len(v)))
+		case []byte:
+			ret = append(ret, fmt.Sprintf("[]byte(%d)", len(v)))
+		default:
+			ret = append(ret, fmt.Sprintf("%T", arg))
+		}
+	}
+
+	return ret
+}
+
+// WithName query name for queries without name comment
+func WithName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, keyName, name)
+}
+
+// WithDatabase metrics database label and span db.system
+func WithDatabase(database string) Option {
+	return func(args *options) {
+		args.database = database
+	}
+}
+
+// WithSlowThreshold log queries taking longer, zero disables
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(args *options) {
+		args.slowThreshold = threshold
+	}
+}
+
+// WithLogger slow queries logger, slog.Default by default
+func WithLogger(logger *slog.Logger) Option {
+	return func(args *options) {
+		args.logger = logger
+	}
+}
+
+// WithTracer spans tracer, otel global tracer provider by default
+func WithTracer(tracer trace.Tracer) Option {
+	return func(args *options) {
+		args.tracer = tracer
+	}
+}
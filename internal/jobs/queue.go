@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/repos"
+)
+
+type (
+	// Queue enqueue jobs to run by Worker
+	Queue struct {
+		repo *repos.Jobs
+	}
+
+	enqueueOptions struct {
+		runAt       time.Time
+		uniqueKey   *string
+		maxAttempts int
+	}
+
+	EnqueueOption func(*enqueueOptions)
+)
+
+var (
+	// MaxAttemptsDefault job attempts before it is failed
+	MaxAttemptsDefault = 10
+
+	// ErrMaxAttemptsInvalid job would never run
+	ErrMaxAttemptsInvalid = errors.New("max attempts should be at least 1")
+)
+
+// NewQueue .
+func NewQueue(repo *repos.Jobs) *Queue {
+	return &Queue{
+		repo: repo,
+	}
+}
+
+// Enqueue job of handler name with payload marshaled to json.
+// Job is enqueued within transaction of context if open, so it is dropped on rollback.
+// entities.ErrAlreadyExists if job is Unique and pending one exists,
+// ErrMaxAttemptsInvalid if MaxAttempts is less than 1
+func (q *Queue) Enqueue(ctx context.Context, name string, payload any, opts ...EnqueueOption) (id int64, err error) {
+	args := &enqueueOptions{
+		maxAttempts: MaxAttemptsDefault,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	if args.maxAttempts < 1 {
+		return 0, ErrMaxAttemptsInvalid
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	runAt := args.runAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	id, err = q.repo.EnqueueJob(ctx, entities.Job{
+		Name:        name,
+		Payload:     data,
+		UniqueKey:   args.uniqueKey,
+		MaxAttempts: args.maxAttempts,
+		RunAt:       runAt,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("enqueue: %w", err)
+	}
+
+	return id, nil
+}
+
+// Delay run job after delay
+func Delay(delay time.Duration) EnqueueOption {
+	return func(args *enqueueOptions) {
+		args.runAt = time.Now().Add(delay)
+	}
+}
+
+// RunAt run job at scheduled time
+func RunAt(t time.Time) EnqueueOption {
+	return func(args *enqueueOptions) {
+		args.runAt = t
+	}
+}
+
+// Unique skip job while pending job with same name and key exists,
+// e.g. one cleanup of user at time
+func Unique(key string) EnqueueOption {
+	return func(args *enqueueOptions) {
+		args.uniqueKey = &key
+	}
+}
+
+// MaxAttempts job is failed after maxAttempts, 1 disables retries, less than 1 is invalid
+func MaxAttempts(maxAttempts int) EnqueueOption {
+	return func(args *enqueueOptions) {
+		args.maxAttempts = maxAttempts
+	}
+}
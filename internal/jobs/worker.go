@@ -0,0 +1,392 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/metrics"
+	"github.com/andrdru/go-template/internal/repos"
+)
+
+type (
+	// Worker run enqueued jobs with registered handlers in pool of goroutines.
+	// Jobs are claimed with FOR UPDATE SKIP LOCKED and locked for visibility timeout,
+	// job of crashed worker is taken by other one after it. Handler runs at least once,
+	// it should be idempotent
+	Worker struct {
+		logger   *slog.Logger
+		repo     *repos.Jobs
+		handlers map[string]handler
+
+		pollInterval      time.Duration
+		visibilityTimeout time.Duration
+		backoffBase       time.Duration
+		backoffMax        time.Duration
+
+		// ctx of handlers, canceled if Stop deadline is exceeded
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		slots    chan struct{}
+		wake     chan struct{}
+		wg       sync.WaitGroup
+		stop     chan struct{}
+		stopOnce sync.Once
+		done     chan struct{}
+	}
+
+	handler func(ctx context.Context, payload []byte) error
+
+	workerOptions struct {
+		concurrency       int
+		pollInterval      time.Duration
+		visibilityTimeout time.Duration
+		backoffBase       time.Duration
+		backoffMax        time.Duration
+	}
+
+	WorkerOption func(*workerOptions)
+
+	// Purge payload of JobPurge
+	Purge struct {
+		Before time.Time `json:"before"`
+	}
+)
+
+const (
+	// JobPurge delete jobs finished before, handled by every Worker
+	JobPurge = "jobs.purge"
+)
+
+var (
+	// ConcurrencyDefault jobs run at once
+	ConcurrencyDefault = 10
+	// PollIntervalDefault pause when no jobs are due
+	PollIntervalDefault = time.Second
+	// VisibilityTimeoutDefault job run deadline
+	VisibilityTimeoutDefault = 5 * time.Minute
+	// BackoffBaseDefault delay after first failed attempt, doubled for every next one
+	BackoffBaseDefault = 5 * time.Second
+	// BackoffMaxDefault .
+	BackoffMaxDefault = time.Hour
+
+	// ErrPermanent handler error wrapping it fails job without retries
+	ErrPermanent = errors.New("permanent job error")
+
+	// statusTimeout saving attempt result deadline
+	statusTimeout = 5 * time.Second
+)
+
+// NewWorker .
+func NewWorker(logger *slog.Logger, repo *repos.Jobs, opts ...WorkerOption) *Worker {
+	args := &workerOptions{
+		concurrency:       ConcurrencyDefault,
+		pollInterval:      PollIntervalDefault,
+		visibilityTimeout: VisibilityTimeoutDefault,
+		backoffBase:       BackoffBaseDefault,
+		backoffMax:        BackoffMaxDefault,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &Worker{
+		logger:            logger,
+		repo:              repo,
+		handlers:          make(map[string]handler),
+		pollInterval:      args.pollInterval,
+		visibilityTimeout: args.visibilityTimeout,
+		backoffBase:       args.backoffBase,
+		backoffMax:        args.backoffMax,
+		ctx:               ctx,
+		cancel:            cancel,
+		slots:             make(chan struct{}, args.concurrency),
+		wake:              make(chan struct{}, 1),
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+
+	Register(w, JobPurge, w.purge)
+
+	return w
+}
+
+// Register handler of jobs by name, payload is decoded from json.
+// Must be called before Run, jobs without handler are left to other workers
+func Register[T any](w *Worker, name string, handle func(ctx context.Context, payload T) error) {
+	w.handlers[name] = func(ctx context.Context, data []byte) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return fmt.Errorf("%w: unmarshal payload: %s", ErrPermanent, err.Error())
+		}
+
+		return handle(ctx, payload)
+	}
+}
+
+// Run claim and run jobs until Stop
+func (w *Worker) Run() {
+	defer close(w.done)
+
+	names := make([]string, 0, len(w.handlers))
+	for name := range w.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			w.wg.Wait()
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+
+		free := cap(w.slots) - len(w.slots)
+		claimed, err := w.claim(names, free)
+		if err != nil {
+			w.logger.Error("claim jobs", slog.Any("error", err))
+		}
+
+		// more jobs are probably due
+		wait := w.pollInterval
+		if err == nil && claimed > 0 && claimed == free {
+			wait = 0
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// Stop claiming jobs, wait for jobs in progress.
+// Handlers context is canceled if ctx is done first
+func (w *Worker) Stop(ctx context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// claim up to limit jobs and run them, returns claimed count
+func (w *Worker) claim(names []string, limit int) (int, error) {
+	if limit <= 0 || len(names) == 0 {
+		return 0, nil
+	}
+
+	jobs, err := w.repo.ClaimJobs(w.ctx, names, limit, w.visibilityTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		w.slots <- struct{}{}
+		w.wg.Add(1)
+
+		go w.process(job)
+	}
+
+	return len(jobs), nil
+}
+
+// process run job attempt and save result
+func (w *Worker) process(job entities.Job) {
+	defer func() {
+		<-w.slots
+		w.wg.Done()
+
+		// free slot, poll without waiting for interval
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}()
+
+	gauge := metrics.GaugeJobsRunning(job.Name)
+	gauge.Inc()
+	defer gauge.Dec()
+
+	start := time.Now()
+
+	var errRun error
+	if job.Attempts > job.MaxAttempts {
+		// last attempt lock expired, e.g. worker crashed
+		errRun = fmt.Errorf("%w: attempts exhausted by visibility timeout", ErrPermanent)
+	} else {
+		// job is taken by other worker after visibility timeout
+		ctx, cancel := context.WithTimeout(w.ctx, w.visibilityTimeout)
+		errRun = w.run(ctx, job)
+		cancel()
+	}
+
+	result := w.finish(job, errRun)
+
+	metrics.ObserveJob(job.Name, result, time.Since(start).Seconds())
+}
+
+// run handler, panic is returned as error
+func (w *Worker) run(ctx context.Context, job entities.Job) (err error) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			metrics.CounterPanics("jobs").Inc()
+
+			w.logger.Error("panic",
+				slog.Int64("job_id", job.ID),
+				slog.String("job", job.Name),
+				slog.Any("error", rcv),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			err = fmt.Errorf("panic: %v", rcv)
+		}
+	}()
+
+	return w.handlers[job.Name](ctx, job.Payload)
+}
+
+// finish save attempt result, returns metrics result
+func (w *Worker) finish(job entities.Job, errRun error) (result string) {
+	// result is saved on shutdown too
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(w.ctx), statusTimeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case errRun == nil:
+		result = "done"
+		err = w.repo.CompleteJob(ctx, job.ID, job.Attempts)
+
+	case errors.Is(errRun, ErrPermanent) || job.Attempts >= job.MaxAttempts:
+		result = "failed"
+		err = w.repo.FailJob(ctx, job.ID, job.Attempts, errRun.Error())
+
+		w.logger.Error("job failed",
+			slog.Int64("job_id", job.ID),
+			slog.String("job", job.Name),
+			slog.Int("attempt", job.Attempts),
+			slog.Any("error", errRun),
+		)
+
+	default:
+		result = "retry"
+		runAt := time.Now().Add(backoff(job.Attempts, w.backoffBase, w.backoffMax))
+		err = w.repo.RetryJob(ctx, job.ID, job.Attempts, runAt, errRun.Error())
+
+		w.logger.Warn("job attempt failed",
+			slog.Int64("job_id", job.ID),
+			slog.String("job", job.Name),
+			slog.Int("attempt", job.Attempts),
+			slog.Time("run_at", runAt),
+			slog.Any("error", errRun),
+		)
+	}
+
+	if errors.Is(err, entities.ErrNotFound) {
+		// visibility timeout exceeded, job was claimed again
+		w.logger.Warn("job lock lost",
+			slog.Int64("job_id", job.ID),
+			slog.String("job", job.Name),
+			slog.Int("attempt", job.Attempts),
+		)
+
+		return "lost"
+	}
+
+	if err != nil {
+		w.logger.Error("save job result",
+			slog.Int64("job_id", job.ID),
+			slog.String("job", job.Name),
+			slog.Any("error", err),
+		)
+	}
+
+	return result
+}
+
+// purge finished jobs, payload of JobPurge
+func (w *Worker) purge(ctx context.Context, payload Purge) error {
+	count, err := w.repo.PurgeJobs(ctx, payload.Before)
+	if err != nil {
+		return fmt.Errorf("purge jobs: %w", err)
+	}
+
+	w.logger.Info("jobs purged", slog.Int64("count", count))
+
+	return nil
+}
+
+// backoff exponential delay of attempt, half of it is jittered
+func backoff(attempt int, base time.Duration, maxDelay time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+
+	if d > maxDelay {
+		d = maxDelay
+	}
+
+	if d <= 1 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// WithConcurrency jobs run at once
+func WithConcurrency(concurrency int) WorkerOption {
+	return func(args *workerOptions) {
+		args.concurrency = concurrency
+	}
+}
+
+// WithPollInterval .
+func WithPollInterval(interval time.Duration) WorkerOption {
+	return func(args *workerOptions) {
+		args.pollInterval = interval
+	}
+}
+
+// WithVisibilityTimeout job run deadline, job is taken by other worker after
+func WithVisibilityTimeout(timeout time.Duration) WorkerOption {
+	return func(args *workerOptions) {
+		args.visibilityTimeout = timeout
+	}
+}
+
+// WithBackoff exponential delay between attempts
+func WithBackoff(base time.Duration, maxDelay time.Duration) WorkerOption {
+	return func(args *workerOptions) {
+		args.backoffBase = base
+		args.backoffMax = maxDelay
+	}
+}
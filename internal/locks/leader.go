@@ -0,0 +1,242 @@
+package locks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Leader election by session lock, one of app replicas leads at time.
+	// Lock is held by dedicated connection, pg_locks is checked every renew interval:
+	// Postgres releases lock of broken connection, so leadership is lost with it
+	Leader struct {
+		logger *slog.Logger
+		locker *Locker
+		name   string
+		lead   func(ctx context.Context)
+
+		retryInterval time.Duration
+		renewInterval time.Duration
+		renewTimeout  time.Duration
+
+		leader   atomic.Bool
+		stop     chan struct{}
+		stopOnce sync.Once
+		done     chan struct{}
+	}
+
+	leaderOptions struct {
+		retryInterval time.Duration
+		renewInterval time.Duration
+		renewTimeout  time.Duration
+	}
+
+	LeaderOption func(*leaderOptions)
+)
+
+var (
+	// RetryIntervalDefault follower tries to acquire leadership
+	RetryIntervalDefault = 5 * time.Second
+	// RenewIntervalDefault leader checks its lock is held
+	RenewIntervalDefault = 5 * time.Second
+	// RenewTimeoutDefault lock check deadline
+	RenewTimeoutDefault = 2 * time.Second
+
+	// ErrLeadershipLost cause of lead context cancel, see context.Cause
+	ErrLeadershipLost = errors.New("leadership lost")
+	// ErrLeaderStopped cause of lead context cancel on Stop
+	ErrLeaderStopped = errors.New("leader stopped")
+)
+
+// NewLeader lead is run while leadership is held,
+// its context is canceled with cause ErrLeadershipLost or ErrLeaderStopped
+func NewLeader(logger *slog.Logger, db *sql.DB, name string, lead func(ctx context.Context), opts ...LeaderOption) *Leader {
+	args := &leaderOptions{
+		retryInterval: RetryIntervalDefault,
+		renewInterval: RenewIntervalDefault,
+		renewTimeout:  RenewTimeoutDefault,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	return &Leader{
+		logger:        logger,
+		locker:        NewLocker(db, nil),
+		name:          name,
+		lead:          lead,
+		retryInterval: args.retryInterval,
+		renewInterval: args.renewInterval,
+		renewTimeout:  args.renewTimeout,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Run campaign for leadership until Stop
+func (l *Leader) Run() {
+	defer close(l.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-l.stop
+		cancel()
+	}()
+
+	for {
+		lock, err := l.locker.TryLock(ctx, l.name)
+		switch {
+		case err == nil:
+			l.hold(lock)
+		case errors.Is(err, ErrLocked):
+		default:
+			if ctx.Err() == nil {
+				l.logger.Error("leader lock", slog.String("name", l.name), slog.Any("error", err))
+			}
+		}
+
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(l.retryInterval):
+		}
+	}
+}
+
+// Stop campaign, leadership is released after lead returns
+func (l *Leader) Stop(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsLeader leadership is held now
+func (l *Leader) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// hold run lead and renew lock until leadership is lost, lead returns or Stop
+func (l *Leader) hold(lock *Lock) {
+	l.leader.Store(true)
+	defer l.leader.Store(false)
+
+	l.logger.Info("leadership acquired", slog.String("name", l.name))
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	leading := make(chan struct{})
+	go func() {
+		defer close(leading)
+		l.lead(ctx)
+	}()
+
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			cancel(ErrLeaderStopped)
+			<-leading
+			l.release(lock)
+
+			return
+
+		case <-leading:
+			// lead finished itself, leadership is campaigned for again
+			l.release(lock)
+
+			return
+
+		case <-ticker.C:
+			if err := l.renew(lock); err != nil {
+				cancel(ErrLeadershipLost)
+				discard(lock.conn)
+
+				l.logger.Warn("leadership lost", slog.String("name", l.name), slog.Any("error", err))
+
+				<-leading
+
+				return
+			}
+		}
+	}
+}
+
+// release leadership lock
+func (l *Leader) release(lock *Lock) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.renewTimeout)
+	defer cancel()
+
+	if err := lock.Unlock(ctx); err != nil {
+		l.logger.Error("leadership release", slog.String("name", l.name), slog.Any("error", err))
+		return
+	}
+
+	l.logger.Info("leadership released", slog.String("name", l.name))
+}
+
+// renew check lock is still held by its connection
+func (l *Leader) renew(lock *Lock) error {
+	// bigint key is split in pg_locks: high half is classid, low half is objid
+	const query = `-- name: lock_held
+SELECT EXISTS(SELECT 1
+              FROM pg_locks
+              WHERE locktype = 'advisory'
+                AND classid = $1
+                AND objid = $2
+                AND objsubid = 1
+                AND pid = pg_backend_pid()
+                AND granted)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.renewTimeout)
+	defer cancel()
+
+	var held bool
+	err := lock.conn.QueryRowContext(ctx, query, uint32(uint64(lock.key)>>32), uint32(lock.key)).Scan(&held)
+	if err != nil {
+		return fmt.Errorf("check lock: %w", err)
+	}
+
+	if !held {
+		return ErrNotHeld
+	}
+
+	return nil
+}
+
+// WithRetryInterval .
+func WithRetryInterval(interval time.Duration) LeaderOption {
+	return func(args *leaderOptions) {
+		args.retryInterval = interval
+	}
+}
+
+// WithRenewInterval .
+func WithRenewInterval(interval time.Duration) LeaderOption {
+	return func(args *leaderOptions) {
+		args.renewInterval = interval
+	}
+}
+
+// WithRenewTimeout lock check deadline, leadership is lost if exceeded
+func WithRenewTimeout(timeout time.Duration) LeaderOption {
+	return func(args *leaderOptions) {
+		args.renewTimeout = timeout
+	}
+}
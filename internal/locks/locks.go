@@ -0,0 +1,173 @@
+package locks
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/andrdru/go-template/tx"
+)
+
+type (
+	// Locker Postgres advisory locks keyed by name.
+	// Session lock is held by dedicated connection until Unlock,
+	// transaction lock is released on commit or rollback of transaction in context
+	Locker struct {
+		db *sql.DB
+		tx transactor
+	}
+
+	// Lock session lock, connection is held until Unlock
+	Lock struct {
+		name string
+		key  int64
+		conn *sql.Conn
+	}
+
+	transactor interface {
+		DB(ctx context.Context) (db tx.QueryExecutor)
+	}
+)
+
+var (
+	// ErrLocked lock is held by other session
+	ErrLocked = errors.New("locked")
+	// ErrNoTx transaction lock is requested out of transaction
+	ErrNoTx = errors.New("no transaction in context")
+	// ErrNotHeld lock was released already, e.g. connection was lost
+	ErrNotHeld = errors.New("lock is not held")
+)
+
+// NewLocker db provides session locks connections, transactor runs transaction locks
+func NewLocker(db *sql.DB, transactor transactor) *Locker {
+	return &Locker{
+		db: db,
+		tx: transactor,
+	}
+}
+
+// TryLock session lock without waiting, ErrLocked if held by other session
+func (l *Locker) TryLock(ctx context.Context, name string) (*Lock, error) {
+	return l.lock(ctx, name, `-- name: lock_try
+SELECT pg_try_advisory_lock($1)`)
+}
+
+// Lock session lock, waits until lock is released by other session or ctx is done
+func (l *Locker) Lock(ctx context.Context, name string) (*Lock, error) {
+	return l.lock(ctx, name, `-- name: lock_wait
+SELECT true FROM pg_advisory_lock($1)`)
+}
+
+func (l *Locker) lock(ctx context.Context, name string, query string) (*Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get conn: %w", err)
+	}
+
+	key := Key(name)
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, query, key).Scan(&acquired)
+	if err != nil {
+		// lock may be granted while query is canceled
+		discard(conn)
+		return nil, fmt.Errorf("lock %s: %w", name, err)
+	}
+
+	if !acquired {
+		_ = conn.Close()
+		return nil, ErrLocked
+	}
+
+	return &Lock{
+		name: name,
+		key:  key,
+		conn: conn,
+	}, nil
+}
+
+// Unlock release lock and connection
+func (l *Lock) Unlock(ctx context.Context) error {
+	const query = `-- name: lock_release
+SELECT pg_advisory_unlock($1)`
+
+	var released bool
+	err := l.conn.QueryRowContext(ctx, query, l.key).Scan(&released)
+	if errors.Is(err, sql.ErrConnDone) {
+		return ErrNotHeld
+	}
+
+	if err != nil {
+		// connection with lock must not return to pool
+		discard(l.conn)
+		return fmt.Errorf("unlock %s: %w", l.name, err)
+	}
+
+	_ = l.conn.Close()
+
+	if !released {
+		return ErrNotHeld
+	}
+
+	return nil
+}
+
+// TryXactLock transaction lock without waiting, ErrLocked if held by other session
+func (l *Locker) TryXactLock(ctx context.Context, name string) error {
+	if !tx.InTX(ctx) {
+		return ErrNoTx
+	}
+
+	const query = `-- name: lock_xact_try
+SELECT pg_try_advisory_xact_lock($1)`
+
+	var acquired bool
+	err := l.tx.DB(ctx).QueryRowContext(ctx, query, Key(name)).Scan(&acquired)
+	if err != nil {
+		return fmt.Errorf("lock %s: %w", name, err)
+	}
+
+	if !acquired {
+		return ErrLocked
+	}
+
+	return nil
+}
+
+// XactLock transaction lock, waits until lock is released by other session or ctx is done
+func (l *Locker) XactLock(ctx context.Context, name string) error {
+	if !tx.InTX(ctx) {
+		return ErrNoTx
+	}
+
+	const query = `-- name: lock_xact_wait
+SELECT pg_advisory_xact_lock($1)`
+
+	_, err := l.tx.DB(ctx).ExecContext(ctx, query, Key(name))
+	if err != nil {
+		return fmt.Errorf("lock %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Key advisory lock key of name: 64-bit FNV-1a hash
+// keys share space with other advisory locks of database
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+
+	return int64(h.Sum64())
+}
+
+// discard close connection instead of returning it to pool,
+// so session locks of connection are released
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+	_ = conn.Close()
+}
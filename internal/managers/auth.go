@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/andrdru/go-template/internal/ctxsess"
@@ -14,28 +15,85 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/hub"
 	"github.com/andrdru/go-template/internal/middlewares"
 	"github.com/andrdru/go-template/internal/repos"
+	"github.com/andrdru/go-template/tx"
 )
 
-type Auth struct {
-	userRepo *repos.User
-}
+type (
+	Auth struct {
+		userRepo *repos.User
+
+		cookieSecure   bool
+		cookieSameSite http.SameSite
+		events         eventPublisher
+		tx             transactor
+		outbox         eventOutbox
+	}
+
+	authOptions struct {
+		cookieSecure   bool
+		cookieSameSite http.SameSite
+		events         eventPublisher
+		tx             transactor
+		outbox         eventOutbox
+	}
+
+	eventPublisher interface {
+		Publish(topic string, name string, data []byte) (hub.Event, error)
+	}
+
+	transactor interface {
+		TX(ctx context.Context, processor func(txCtx context.Context) error, opts ...tx.Option) error
+	}
+
+	eventOutbox interface {
+		Enqueue(ctx context.Context, event entities.OutboxEvent) error
+	}
+
+	// SessionCreated payload of outbox EventSessionCreated
+	SessionCreated struct {
+		UserID int64 `json:"user_id"`
+	}
+
+	AuthOption func(*authOptions)
+)
 
 const (
-	headerUserSession = "X-User-Session"
+	// CookieUserSession session cookie name
+	CookieUserSession = "X-User-Session"
 
 	cookieTokenStoreDuration = 3 * 30 * 24 * time.Hour
+
+	// EventSessionCreated user logged in
+	EventSessionCreated = "session.created"
+
+	// OutboxTopicUsers outbox topic of user events
+	OutboxTopicUsers = "users"
 )
 
-func NewAuth(userRepo *repos.User) *Auth {
+func NewAuth(userRepo *repos.User, opts ...AuthOption) *Auth {
+	args := &authOptions{
+		cookieSameSite: http.SameSiteDefaultMode,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
 	return &Auth{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		cookieSecure:   args.cookieSecure,
+		cookieSameSite: args.cookieSameSite,
+		events:         args.events,
+		tx:             args.tx,
+		outbox:         args.outbox,
 	}
 }
 
 func (a *Auth) Check(r *http.Request) (ctx context.Context, err error) {
-	cookie, err := r.Cookie(headerUserSession)
+	cookie, err := r.Cookie(CookieUserSession)
 	if err != nil {
 		return nil, fmt.Errorf("get cookie: %w", err)
 	}
@@ -45,12 +103,20 @@ func (a *Auth) Check(r *http.Request) (ctx context.Context, err error) {
 		return nil, fmt.Errorf("sessionDataFromCookie: %w", err)
 	}
 
-	session, err = a.getSessionByToken(r.Context(), session.Token)
+	return a.CheckToken(r.Context(), session.Token)
+}
+
+// CheckToken validate session token, put session to context
+func (a *Auth) CheckToken(ctx context.Context, token string) (context.Context, error) {
+	session, err := a.getSessionByToken(ctx, token)
 	if err != nil {
 		return nil, fmt.Errorf("getSessionByToken: %w", err)
 	}
 
-	return ctxsess.Set(r.Context(), session), nil
+	// user reads own writes despite replica lag
+	ctx = tx.WithStickyKey(ctx, strconv.FormatInt(session.UserID, 10))
+
+	return ctxsess.Set(ctx, session), nil
 }
 
 func (a *Auth) Login(
@@ -58,29 +124,79 @@ func (a *Auth) Login(
 	w http.ResponseWriter,
 	session entities.Session,
 ) error {
+	session, err := a.CreateSession(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	err = a.setSessionCookie(w, &session)
+	if err != nil {
+		return fmt.Errorf("setSessionCookie: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSession check credentials, return session with token
+func (a *Auth) CreateSession(ctx context.Context, session entities.Session) (entities.Session, error) {
 	getUser, err := a.userRepo.User(ctx, session.Email)
 	if err != nil {
-		return fmt.Errorf("get user: %w", err)
+		return entities.Session{}, fmt.Errorf("get user: %w", err)
 	}
 
 	if !checkPasswordHash(session.Pass, getUser.Passhash) {
-		return entities.ErrNotAllowed
+		return entities.Session{}, entities.ErrNotAllowed
 	}
 
 	session.UserID = getUser.ID
 	session.Token = uuid.NewString()
 
-	err = a.userRepo.CreateSession(ctx, session)
+	err = a.createSession(ctx, session)
 	if err != nil {
-		return fmt.Errorf("create session: %w", err)
+		return entities.Session{}, err
 	}
 
-	err = setSessionCookie(w, &session)
-	if err != nil {
-		return fmt.Errorf("setSessionCookie: %w", err)
+	if a.events != nil {
+		data, _ := json.Marshal(session.Extra)
+		// notify other sessions of user, nobody listening is not an error
+		_, _ = a.events.Publish(TopicUser(session.UserID), EventSessionCreated, data)
 	}
 
-	return nil
+	return session, nil
+}
+
+// createSession save session, outbox event is enqueued in same transaction
+func (a *Auth) createSession(ctx context.Context, session entities.Session) error {
+	if a.outbox == nil {
+		err := a.userRepo.CreateSession(ctx, session)
+		if err != nil {
+			return fmt.Errorf("create session: %w", err)
+		}
+
+		return nil
+	}
+
+	return a.tx.TX(ctx, func(txCtx context.Context) error {
+		err := a.userRepo.CreateSession(txCtx, session)
+		if err != nil {
+			return fmt.Errorf("create session: %w", err)
+		}
+
+		// marshal of plain struct does not fail
+		payload, _ := json.Marshal(SessionCreated{UserID: session.UserID})
+
+		err = a.outbox.Enqueue(txCtx, entities.OutboxEvent{
+			Topic:   OutboxTopicUsers,
+			Name:    EventSessionCreated,
+			Key:     strconv.FormatInt(session.UserID, 10),
+			Payload: payload,
+		})
+		if err != nil {
+			return fmt.Errorf("enqueue event: %w", err)
+		}
+
+		return nil
+	}, tx.WithLabel("session_create"))
 }
 
 func (a *Auth) getSessionByToken(ctx context.Context, token string) (session *entities.Session, err error) {
@@ -115,18 +231,54 @@ func sessionDataFromCookie(cookie *http.Cookie) (session *entities.Session, err
 	return session, nil
 }
 
-func setSessionCookie(w http.ResponseWriter, session *entities.Session) error {
+func (a *Auth) setSessionCookie(w http.ResponseWriter, session *entities.Session) error {
 	data, err := json.Marshal(session)
 	if err != nil {
 		return err
 	}
 	http.SetCookie(w, &http.Cookie{
-		Name:     headerUserSession,
+		Name:     CookieUserSession,
 		Value:    base64.URLEncoding.EncodeToString(data),
 		Expires:  time.Now().Add(cookieTokenStoreDuration),
 		Path:     "/",
 		HttpOnly: true,
+		Secure:   a.cookieSecure,
+		SameSite: a.cookieSameSite,
 	})
 
 	return nil
 }
+
+// WithCookieSecure set Secure attribute of session cookie
+func WithCookieSecure(secure bool) AuthOption {
+	return func(args *authOptions) {
+		args.cookieSecure = secure
+	}
+}
+
+// WithCookieSameSite set SameSite attribute of session cookie
+func WithCookieSameSite(sameSite http.SameSite) AuthOption {
+	return func(args *authOptions) {
+		args.cookieSameSite = sameSite
+	}
+}
+
+// WithEvents publish user events, e.g. new session
+func WithEvents(events eventPublisher) AuthOption {
+	return func(args *authOptions) {
+		args.events = events
+	}
+}
+
+// WithOutbox enqueue user events for external consumers within transaction of write
+func WithOutbox(transactor transactor, events eventOutbox) AuthOption {
+	return func(args *authOptions) {
+		args.tx = transactor
+		args.outbox = events
+	}
+}
+
+// TopicUser events topic of user
+func TopicUser(userID int64) string {
+	return "user." + strconv.FormatInt(userID, 10)
+}
@@ -0,0 +1,141 @@
+package managers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/middlewares"
+	"github.com/andrdru/go-template/internal/repos"
+)
+
+type Idempotency struct {
+	repo *repos.Idempotency
+
+	lockTimeout time.Duration
+	ttl         time.Duration
+
+	// purgeAt next expired keys purge, unix nano
+	purgeAt atomic.Int64
+}
+
+var (
+	// IdempotencyLockTimeoutDefault time to finish request before lock may be taken over
+	IdempotencyLockTimeoutDefault = time.Minute
+	// IdempotencyTTLDefault how long saved response is replayed
+	IdempotencyTTLDefault = 24 * time.Hour
+	// IdempotencyPurgeInterval how often expired keys are purged by each app instance
+	IdempotencyPurgeInterval = time.Hour
+
+	// idempotencyPurgeTimeout .
+	idempotencyPurgeTimeout = time.Minute
+)
+
+func NewIdempotency(repo *repos.Idempotency, lockTimeout time.Duration, ttl time.Duration) *Idempotency {
+	if lockTimeout <= 0 {
+		lockTimeout = IdempotencyLockTimeoutDefault
+	}
+
+	if ttl <= 0 {
+		ttl = IdempotencyTTLDefault
+	}
+
+	return &Idempotency{
+		repo:        repo,
+		lockTimeout: lockTimeout,
+		ttl:         ttl,
+	}
+}
+
+// Begin lock key for request processing, lockToken identifies lock owner in Complete and Release.
+// returns saved response if request was processed already
+func (i *Idempotency) Begin(
+	ctx context.Context,
+	key string,
+	fingerprint string,
+) (lockToken string, resp *entities.IdempotencyResponse, err error) {
+	now := time.Now()
+	i.purge(now)
+
+	lockToken = uuid.NewString()
+
+	acquired, err := i.repo.LockIdempotency(ctx, key, fingerprint, lockToken, now.Add(i.lockTimeout), now.Add(-i.ttl))
+	if err != nil {
+		return "", nil, fmt.Errorf("lock: %w", err)
+	}
+
+	if acquired {
+		return lockToken, nil, nil
+	}
+
+	item, err := i.repo.Idempotency(ctx, key)
+	if err != nil {
+		// released concurrently, let client retry
+		if errors.Is(err, entities.ErrNotFound) {
+			return "", nil, middlewares.ErrIdempotencyInProgress
+		}
+
+		return "", nil, fmt.Errorf("get: %w", err)
+	}
+
+	if item.Fingerprint != fingerprint {
+		return "", nil, middlewares.ErrIdempotencyMismatch
+	}
+
+	if item.Response == nil {
+		return "", nil, middlewares.ErrIdempotencyInProgress
+	}
+
+	return "", item.Response, nil
+}
+
+// Complete save response for replay, middlewares.ErrIdempotencyLockLost if lock is taken over
+func (i *Idempotency) Complete(ctx context.Context, key string, lockToken string, resp entities.IdempotencyResponse) error {
+	err := i.repo.SaveIdempotencyResponse(ctx, key, lockToken, resp)
+	if err != nil {
+		if errors.Is(err, entities.ErrNotFound) {
+			return middlewares.ErrIdempotencyLockLost
+		}
+
+		return fmt.Errorf("save: %w", err)
+	}
+
+	return nil
+}
+
+// Release unlock key without response, next retry is processed again
+func (i *Idempotency) Release(ctx context.Context, key string, lockToken string) error {
+	err := i.repo.DeleteIdempotency(ctx, key, lockToken)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// purge delete expired keys in background once per IdempotencyPurgeInterval
+func (i *Idempotency) purge(now time.Time) {
+	purgeAt := i.purgeAt.Load()
+	if now.UnixNano() < purgeAt || !i.purgeAt.CompareAndSwap(purgeAt, now.Add(IdempotencyPurgeInterval).UnixNano()) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), idempotencyPurgeTimeout)
+		defer cancel()
+
+		count, err := i.repo.PurgeIdempotency(ctx, now.Add(-i.ttl))
+		if err != nil {
+			slog.Default().Error("purge idempotency keys", slog.Any("error", err))
+			return
+		}
+
+		slog.Default().Info("idempotency keys purged", slog.Int64("count", count))
+	}()
+}
@@ -0,0 +1,122 @@
+package managers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/jobs"
+	"github.com/andrdru/go-template/internal/outbox"
+)
+
+const (
+	// JobSessionsPurge delete logged out sessions, payload is SessionsPurge
+	JobSessionsPurge = "sessions.purge"
+)
+
+type (
+	SessionsPurge struct {
+		Before time.Time `json:"before"`
+	}
+
+	// Scheduler enqueue periodic app jobs, run by leader replica only
+	Scheduler struct {
+		logger *slog.Logger
+		queue  *jobs.Queue
+
+		interval time.Duration
+		periodic []periodicJob
+	}
+
+	// periodicJob is enqueued unique, pending job covers next one
+	periodicJob struct {
+		name    string
+		payload func() any
+	}
+
+	SchedulerOption func(*Scheduler)
+)
+
+// RegisterJobs handlers of app jobs, shared by app and worker script
+func RegisterJobs(w *jobs.Worker, userManager *User, events *outbox.Outbox) {
+	jobs.Register(w, JobSessionsPurge, userManager.PurgeSessions)
+	jobs.Register(w, outbox.JobPurge, events.Purge)
+}
+
+// NewScheduler jobs are enabled by options
+func NewScheduler(logger *slog.Logger, queue *jobs.Queue, interval time.Duration, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		logger:   logger,
+		queue:    queue,
+		interval: interval,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Run enqueue jobs every interval until ctx is done, see locks.Leader
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.schedule(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) schedule(ctx context.Context) {
+	for _, job := range s.periodic {
+		_, err := s.queue.Enqueue(ctx, job.name, job.payload(), jobs.Unique(job.name))
+		if err != nil && !errors.Is(err, entities.ErrAlreadyExists) {
+			s.logger.Error("schedule job", slog.String("job", job.name), slog.Any("error", err))
+		}
+	}
+}
+
+// WithSessionsPurge purge sessions logged out before retention
+func WithSessionsPurge(retention time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.periodic = append(s.periodic, periodicJob{
+			name: JobSessionsPurge,
+			payload: func() any {
+				return SessionsPurge{Before: time.Now().Add(-retention)}
+			},
+		})
+	}
+}
+
+// WithJobsPurge purge jobs finished before retention
+func WithJobsPurge(retention time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.periodic = append(s.periodic, periodicJob{
+			name: jobs.JobPurge,
+			payload: func() any {
+				return jobs.Purge{Before: time.Now().Add(-retention)}
+			},
+		})
+	}
+}
+
+// WithOutboxPurge purge outbox events delivered or dead before retention
+func WithOutboxPurge(retention time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.periodic = append(s.periodic, periodicJob{
+			name: outbox.JobPurge,
+			payload: func() any {
+				return outbox.Purge{Before: time.Now().Add(-retention)}
+			},
+		})
+	}
+}
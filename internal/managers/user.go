@@ -0,0 +1,43 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/pagination"
+	"github.com/andrdru/go-template/internal/repos"
+)
+
+type User struct {
+	userRepo *repos.User
+}
+
+func NewUser(userRepo *repos.User) *User {
+	return &User{
+		userRepo: userRepo,
+	}
+}
+
+// List users page, one extra item is returned if next page exists
+func (u *User) List(ctx context.Context, params pagination.Params) ([]entities.User, error) {
+	users, err := u.userRepo.Users(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("get users: %w", err)
+	}
+
+	return users, nil
+}
+
+// PurgeSessions delete sessions logged out before
+func (u *User) PurgeSessions(ctx context.Context, payload SessionsPurge) error {
+	count, err := u.userRepo.PurgeSessions(ctx, payload.Before)
+	if err != nil {
+		return fmt.Errorf("purge sessions: %w", err)
+	}
+
+	slog.Default().Info("sessions purged", slog.Int64("count", count))
+
+	return nil
+}
@@ -19,6 +19,139 @@ var (
 			Help:      "databases query metrics",
 			Buckets:   []float64{.001, .005, .01, .025, .05, .075, .1, .25, .5, 1, 2.5, 5, 10},
 		}, []string{"database", "name", "error"})
+
+	httpRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "http_requests_total",
+			Help:      "http requests count",
+		}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "http_request_duration_seconds",
+			Help:      "http requests latency",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .075, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"route", "method", "status"})
+
+	httpResponseSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "http_response_size_bytes",
+			Help:      "http response body size",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"route", "method", "status"})
+
+	httpInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "http_requests_in_flight",
+			Help:      "http requests being served",
+		}, []string{"route", "method"})
+
+	panics = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "panics_total",
+			Help:      "recovered panics count",
+		}, []string{"source"})
+
+	rateLimits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "rate_limit_total",
+			Help:      "rate limiter decisions",
+		}, []string{"route", "result"})
+
+	txRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "tx_retries_total",
+			Help:      "retried transactions count",
+		}, []string{"label", "result"})
+
+	txDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "tx_duration_seconds",
+			Help:      "transactions duration",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .075, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"label", "result"})
+
+	grpcRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "grpc_requests_total",
+			Help:      "grpc requests count",
+		}, []string{"method", "code"})
+
+	grpcDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "grpc requests latency",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .075, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"method", "code"})
+
+	websocketConnections = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "websocket_connections",
+			Help:      "open websocket connections",
+		})
+
+	websocketMessages = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "websocket_messages_total",
+			Help:      "websocket messages count",
+		}, []string{"direction"})
+
+	outboxEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "outbox_events_total",
+			Help:      "outbox events delivery attempts",
+		}, []string{"name", "result"})
+
+	jobs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "jobs_total",
+			Help:      "finished job attempts count",
+		}, []string{"name", "result"})
+
+	jobDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "job_duration_seconds",
+			Help:      "job attempts duration",
+			Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+		}, []string{"name", "result"})
+
+	jobsRunning = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "jobs_running",
+			Help:      "jobs in progress",
+		}, []string{"name"})
 )
 
 // HistogramObserverDB .
@@ -29,3 +162,106 @@ func HistogramObserverDB(database string, name string, errFunc func() string) pr
 		"error":    errFunc(),
 	})
 }
+
+// ObserveHTTP record finished http request
+// status is a status class: 2xx, 4xx etc
+func ObserveHTTP(route string, method string, status string, seconds float64, size int) {
+	labels := map[string]string{
+		"route":  route,
+		"method": method,
+		"status": status,
+	}
+
+	httpRequests.With(labels).Inc()
+	httpDuration.With(labels).Observe(seconds)
+	httpResponseSize.With(labels).Observe(float64(size))
+}
+
+// GaugeHTTPInFlight .
+func GaugeHTTPInFlight(route string, method string) prometheus.Gauge {
+	return httpInFlight.With(map[string]string{
+		"route":  route,
+		"method": method,
+	})
+}
+
+// CounterPanics .
+func CounterPanics(source string) prometheus.Counter {
+	return panics.With(map[string]string{
+		"source": source,
+	})
+}
+
+// CounterRateLimit result is one of: allowed, limited, error
+func CounterRateLimit(route string, result string) prometheus.Counter {
+	return rateLimits.With(map[string]string{
+		"route":  route,
+		"result": result,
+	})
+}
+
+// GaugeWebSocketConnections .
+func GaugeWebSocketConnections() prometheus.Gauge {
+	return websocketConnections
+}
+
+// CounterWebSocketMessages direction is one of: in, out, dropped
+func CounterWebSocketMessages(direction string) prometheus.Counter {
+	return websocketMessages.With(map[string]string{
+		"direction": direction,
+	})
+}
+
+// CounterTxRetries result is one of: retry, give_up
+func CounterTxRetries(label string, result string) prometheus.Counter {
+	return txRetries.With(map[string]string{
+		"label":  label,
+		"result": result,
+	})
+}
+
+// HistogramTxDuration result is one of: commit, rollback
+func HistogramTxDuration(label string, result string) prometheus.Observer {
+	return txDuration.With(map[string]string{
+		"label":  label,
+		"result": result,
+	})
+}
+
+// CounterOutboxEvents result is one of: delivered, retry, dead
+func CounterOutboxEvents(name string, result string) prometheus.Counter {
+	return outboxEvents.With(map[string]string{
+		"name":   name,
+		"result": result,
+	})
+}
+
+// ObserveJob record finished job attempt
+// result is one of: done, retry, failed, lost
+func ObserveJob(name string, result string, seconds float64) {
+	labels := map[string]string{
+		"name":   name,
+		"result": result,
+	}
+
+	jobs.With(labels).Inc()
+	jobDuration.With(labels).Observe(seconds)
+}
+
+// GaugeJobsRunning .
+func GaugeJobsRunning(name string) prometheus.Gauge {
+	return jobsRunning.With(map[string]string{
+		"name": name,
+	})
+}
+
+// ObserveGRPC record finished grpc call
+func ObserveGRPC(method string, code string, seconds float64) {
+	labels := map[string]string{
+		"method": method,
+		"code":   code,
+	}
+
+	grpcRequests.With(labels).Inc()
+	grpcDuration.With(labels).Observe(seconds)
+}
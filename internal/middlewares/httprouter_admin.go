@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/andrdru/go-template/internal/ctxsess"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AdminOnly allow admin users only, requires SessionValidate before
+func AdminOnly(forbiddenFunc func(w http.ResponseWriter, message string) error) HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			sess := ctxsess.Get(r.Context())
+			if sess == nil || sess.User == nil || !sess.User.IsAdmin {
+				err := forbiddenFunc(w, "")
+				if err != nil {
+					slog.Default().Error("write forbidden", slog.Any("error", err))
+				}
+
+				return
+			}
+
+			next(w, r, p)
+		}
+	}
+}
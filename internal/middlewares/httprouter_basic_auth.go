@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// BasicAuth require credentials, empty username disables check
+func BasicAuth(
+	username string,
+	password string,
+	unauthorizedFunc func(w http.ResponseWriter, message string) error,
+) HTTPMiddleware {
+	if username == "" {
+		return func(next httprouter.Handle) httprouter.Handle {
+			return next
+		}
+	}
+
+	wantUser := sha256.Sum256([]byte(username))
+	wantPass := sha256.Sum256([]byte(password))
+
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			user, pass, ok := r.BasicAuth()
+
+			gotUser := sha256.Sum256([]byte(user))
+			gotPass := sha256.Sum256([]byte(pass))
+
+			// both compared, constant time
+			userOK := subtle.ConstantTimeCompare(gotUser[:], wantUser[:]) == 1
+			passOK := subtle.ConstantTimeCompare(gotPass[:], wantPass[:]) == 1
+
+			if !ok || !userOK || !passOK {
+				w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+
+				err := unauthorizedFunc(w, "")
+				if err != nil {
+					slog.Default().Error("write unauthorized", slog.Any("error", err))
+				}
+
+				return
+			}
+
+			next(w, r, p)
+		}
+	}
+}
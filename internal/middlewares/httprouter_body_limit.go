@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// BodyLimitDefault request body limit if not configured, bytes
+var BodyLimitDefault int64 = 1 << 20
+
+// BodyLimit limit request body size with http.MaxBytesReader
+// request with bigger Content-Length is rejected at once,
+// otherwise reading body fails with *http.MaxBytesError
+func BodyLimit(limit int64, tooLargeFunc func(w http.ResponseWriter, message string) error) HTTPMiddleware {
+	if limit <= 0 {
+		limit = BodyLimitDefault
+	}
+
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			if r.ContentLength > limit {
+				err := tooLargeFunc(w, "")
+				if err != nil {
+					slog.Default().Error("write request too large", slog.Any("error", err))
+				}
+
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+			next(w, r, p)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CacheControl set route default Cache-Control, handler may override it
+func CacheControl(policy string) HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		if policy == "" {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			w.Header().Set("Cache-Control", policy)
+
+			next(w, r, p)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/andybalholm/brotli"
+	"github.com/julienschmidt/httprouter"
+	"github.com/klauspost/compress/zstd"
+)
+
+type (
+	compressor interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+
+	// zstdCompressor adapt zstd.Encoder Reset signature
+	zstdCompressor struct {
+		*zstd.Encoder
+	}
+
+	// compressWriter buffer response until MinSize is reached,
+	// then decide to compress it or pass as is
+	compressWriter struct {
+		http.ResponseWriter
+
+		conf     *compressConf
+		encoding string
+
+		status  int
+		buf     bytes.Buffer
+		decided bool
+		enc     compressor
+	}
+
+	compressConf struct {
+		minSize      int
+		contentTypes map[string]struct{}
+		encodings    []string
+		excludePaths []string
+	}
+)
+
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+	encodingBr   = "br"
+)
+
+var (
+	// CompressMinSizeDefault .
+	CompressMinSizeDefault = 1024
+	// CompressContentTypesDefault .
+	CompressContentTypesDefault = []string{"application/json", "text/plain", "text/html", "text/css", "application/javascript"}
+	// CompressEncodingsDefault server preference order for equal client weights
+	CompressEncodingsDefault = []string{encodingZstd, encodingBr, encodingGzip}
+	// CompressExcludePathsDefault path prefixes never compressed
+	CompressExcludePathsDefault = []string{"/metrics", "/debug/pprof"}
+
+	compressorPools = map[string]*sync.Pool{
+		encodingGzip: {New: func() any {
+			return gzip.NewWriter(io.Discard)
+		}},
+		encodingZstd: {New: func() any {
+			enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+			return zstdCompressor{enc}
+		}},
+		encodingBr: {New: func() any {
+			return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+		}},
+	}
+)
+
+// Compress compress response body with negotiated Accept-Encoding: zstd, br, gzip
+func Compress(conf configs.Compression) HTTPMiddleware {
+	c := newCompressConf(conf)
+
+	return func(next httprouter.Handle) httprouter.Handle {
+		if !conf.Enabled {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			encoding := c.negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || c.excluded(r.URL.Path) {
+				next(w, r, p)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				conf:           c,
+				encoding:       encoding,
+			}
+			// return encoder to pool on panic too
+			defer cw.release()
+
+			next(cw, r, p)
+
+			_ = cw.Close()
+		}
+	}
+}
+
+func newCompressConf(conf configs.Compression) *compressConf {
+	c := &compressConf{
+		minSize:      conf.MinSize,
+		contentTypes: make(map[string]struct{}),
+		encodings:    conf.Encodings,
+		excludePaths: conf.ExcludePaths,
+	}
+
+	if c.minSize <= 0 {
+		c.minSize = CompressMinSizeDefault
+	}
+
+	if len(c.encodings) == 0 {
+		c.encodings = CompressEncodingsDefault
+	}
+
+	if len(c.excludePaths) == 0 {
+		c.excludePaths = CompressExcludePathsDefault
+	}
+
+	contentTypes := conf.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = CompressContentTypesDefault
+	}
+
+	for _, ct := range contentTypes {
+		c.contentTypes[strings.ToLower(ct)] = struct{}{}
+	}
+
+	return c
+}
+
+// negotiate pick encoding with max client weight, server order on tie
+func (c *compressConf) negotiate(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+
+		weights[name] = q
+	}
+
+	var (
+		best  string
+		bestQ float64
+	)
+
+	for _, encoding := range c.encodings {
+		if _, ok := compressorPools[encoding]; !ok {
+			continue
+		}
+
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+func (c *compressConf) excluded(path string) bool {
+	for _, prefix := range c.excludePaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+
+	w.status = code
+
+	// no body expected, pass as is
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(data)
+		}
+
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf.Write(data)
+	if w.buf.Len() >= w.conf.minSize {
+		err := w.flushBuf(true)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+// Flush implement http.Flusher, small buffered response is sent uncompressed
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.flushBuf(w.buf.Len() >= w.conf.minSize)
+	}
+
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap used by http.ResponseController
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close finish response, small response is sent uncompressed
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		return w.flushBuf(false)
+	}
+
+	if w.enc != nil {
+		return w.enc.Close()
+	}
+
+	return nil
+}
+
+func (w *compressWriter) flushBuf(compress bool) error {
+	w.decide(compress)
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+
+	w.buf.Reset()
+
+	return err
+}
+
+// decide write header, start compression if allowed
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	h := w.Header()
+
+	if compress && w.compressible(h) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		h.Add("Vary", "Accept-Encoding")
+
+		// representation changes, strong etag is not valid anymore
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+
+		w.enc = compressorPools[w.encoding].Get().(compressor)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *compressWriter) compressible(h http.Header) bool {
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	if size, err := strconv.Atoi(h.Get("Content-Length")); err == nil && size < w.conf.minSize {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	_, ok := w.conf.contentTypes[mediaType]
+
+	return ok
+}
+
+func (w *compressWriter) release() {
+	if w.enc == nil {
+		return
+	}
+
+	w.enc.Reset(io.Discard)
+	compressorPools[w.encoding].Put(w.enc)
+	w.enc = nil
+}
+
+func (z zstdCompressor) Reset(w io.Writer) {
+	z.Encoder.Reset(w)
+}
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/julienschmidt/httprouter"
+)
+
+type cors struct {
+	allowAny    bool
+	origins     map[string]struct{}
+	methods     string
+	headers     string
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+const (
+	headerOrigin        = "Origin"
+	headerVary          = "Vary"
+	headerRequestMethod = "Access-Control-Request-Method"
+	headerRequestHeader = "Access-Control-Request-Headers"
+)
+
+var (
+	// CORSAllowedHeadersDefault request headers allowed if configs.CORS AllowedHeaders is empty
+	CORSAllowedHeadersDefault = []string{"Content-Type", "X-Request-ID", "X-CSRF-Token", "Idempotency-Key"}
+)
+
+func newCORS(conf configs.CORS) *cors {
+	c := &cors{
+		origins:     make(map[string]struct{}, len(conf.AllowedOrigins)),
+		methods:     strings.Join(conf.AllowedMethods, ", "),
+		headers:     strings.Join(CORSAllowedHeadersDefault, ", "),
+		exposed:     strings.Join(conf.ExposedHeaders, ", "),
+		credentials: conf.AllowCredentials,
+	}
+
+	for _, origin := range conf.AllowedOrigins {
+		if origin == "*" {
+			c.allowAny = true
+			continue
+		}
+
+		c.origins[strings.ToLower(origin)] = struct{}{}
+	}
+
+	if len(conf.AllowedHeaders) > 0 {
+		c.headers = strings.Join(conf.AllowedHeaders, ", ")
+	}
+
+	if conf.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(conf.MaxAge)
+	}
+
+	return c
+}
+
+// allowOrigin set Access-Control-Allow-Origin if origin allowed
+func (c *cors) allowOrigin(h http.Header, origin string) bool {
+	h.Add(headerVary, headerOrigin)
+
+	if origin == "" {
+		return false
+	}
+
+	_, ok := c.origins[strings.ToLower(origin)]
+	if !ok && !c.allowAny {
+		return false
+	}
+
+	// wildcard never allows credentials, see configs.CORS
+	if c.allowAny {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return true
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	return true
+}
+
+// CORS add cross-origin headers to actual requests
+func CORS(conf configs.CORS) HTTPMiddleware {
+	c := newCORS(conf)
+
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			if c.allowOrigin(w.Header(), r.Header.Get(headerOrigin)) && c.exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+			}
+
+			next(w, r, p)
+		}
+	}
+}
+
+// CORSPreflight handle preflight requests
+// use as httprouter.Router.GlobalOPTIONS, router sets Allow header before call
+func CORSPreflight(conf configs.CORS) http.Handler {
+	c := newCORS(conf)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+
+		method := r.Header.Get(headerRequestMethod)
+		if method == "" || !c.allowOrigin(h, r.Header.Get(headerOrigin)) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		h.Add(headerVary, headerRequestMethod)
+		h.Add(headerVary, headerRequestHeader)
+
+		methods := c.methods
+		if methods == "" {
+			methods = h.Get("Allow")
+		}
+		h.Set("Access-Control-Allow-Methods", methods)
+
+		h.Set("Access-Control-Allow-Headers", c.headers)
+
+		if c.maxAge != "" {
+			h.Set("Access-Control-Max-Age", c.maxAge)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
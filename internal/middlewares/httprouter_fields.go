@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// fieldsWriter carry requested response fields to response writer
+type fieldsWriter struct {
+	http.ResponseWriter
+
+	fields []string
+}
+
+const (
+	// QueryFields comma separated response fields, dot for nested: ?fields=id,user.email
+	QueryFields = "fields"
+
+	fieldsMax = 100
+)
+
+// Fields pass ?fields= query param to response, see SelectedFields
+func Fields() HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			query := r.URL.Query().Get(QueryFields)
+			if query == "" {
+				next(w, r, p)
+				return
+			}
+
+			var fields []string
+			for _, field := range strings.Split(query, ",") {
+				field = strings.TrimSpace(field)
+				if field == "" {
+					continue
+				}
+
+				fields = append(fields, field)
+				if len(fields) == fieldsMax {
+					break
+				}
+			}
+
+			next(&fieldsWriter{ResponseWriter: w, fields: fields}, r, p)
+		}
+	}
+}
+
+// SelectedFields requested response fields, nil if not set
+func SelectedFields(w http.ResponseWriter) []string {
+	for w != nil {
+		if fw, ok := w.(*fieldsWriter); ok {
+			return fw.fields
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+
+		w = u.Unwrap()
+	}
+
+	return nil
+}
+
+// Flush implement http.Flusher
+func (w *fieldsWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap used by http.ResponseController
+func (w *fieldsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
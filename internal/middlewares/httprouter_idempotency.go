@@ -0,0 +1,230 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andrdru/go-template/internal/ctxsess"
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/julienschmidt/httprouter"
+)
+
+type idempotency interface {
+	Begin(ctx context.Context, key string, fingerprint string) (lockToken string, resp *entities.IdempotencyResponse, err error)
+	Complete(ctx context.Context, key string, lockToken string, resp entities.IdempotencyResponse) error
+	Release(ctx context.Context, key string, lockToken string) error
+}
+
+// idempotencyWriter pass response to client and keep a copy
+type idempotencyWriter struct {
+	http.ResponseWriter
+
+	status  int
+	headers http.Header
+	body    bytes.Buffer
+}
+
+const (
+	// HeaderIdempotencyKey .
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed set on replayed responses
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	idempotencyKeyMaxLen = 255
+)
+
+var (
+	ErrIdempotencyInProgress = errors.New("request with same idempotency key in progress")
+	ErrIdempotencyMismatch   = errors.New("idempotency key reused with other request")
+	// ErrIdempotencyLockLost lock is taken over after lock timeout, response is not saved
+	ErrIdempotencyLockLost = errors.New("idempotency key lock taken over")
+
+	// idempotencySkipHeaders are not saved for replay, canonical form
+	idempotencySkipHeaders = map[string]struct{}{
+		"X-Request-Id":        {},
+		"Date":                {},
+		"Retry-After":         {},
+		"Ratelimit-Limit":     {},
+		"Ratelimit-Remaining": {},
+		"Ratelimit-Reset":     {},
+		// session of first request must not be issued to replaying client
+		"Set-Cookie": {},
+	}
+)
+
+// Idempotency replay saved response for requests with same Idempotency-Key header
+// key is scoped by route and session user.
+// same key with other payload gets 422, concurrent duplicate gets 409.
+// server errors are not saved, request with same key is processed again
+func Idempotency(
+	store idempotency,
+	route string,
+	errorFunc func(w http.ResponseWriter, code int, message string) error,
+) HTTPMiddleware {
+	writeError := func(w http.ResponseWriter, code int, message string) {
+		err := errorFunc(w, code, message)
+		if err != nil {
+			slog.Default().Error("write idempotency error", slog.Any("error", err))
+		}
+	}
+
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			key := r.Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				next(w, r, p)
+				return
+			}
+
+			if len(key) > idempotencyKeyMaxLen {
+				writeError(w, http.StatusBadRequest, "idempotency key too long")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			_ = r.Body.Close()
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					writeError(w, http.StatusRequestEntityTooLarge, "")
+					return
+				}
+
+				writeError(w, http.StatusBadRequest, "read body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key = idempotencyScope(r, route, key)
+			fingerprint := idempotencyFingerprint(r, body)
+
+			lockToken, saved, err := store.Begin(r.Context(), key, fingerprint)
+			switch {
+			case errors.Is(err, ErrIdempotencyInProgress):
+				writeError(w, http.StatusConflict, err.Error())
+				return
+			case errors.Is(err, ErrIdempotencyMismatch):
+				writeError(w, http.StatusUnprocessableEntity, err.Error())
+				return
+			case err != nil:
+				slog.Default().Error("idempotency begin", slog.Any("error", err))
+				writeError(w, http.StatusInternalServerError, "")
+				return
+			case saved != nil:
+				idempotencyReplay(w, saved)
+				return
+			}
+
+			iw := &idempotencyWriter{ResponseWriter: w}
+
+			// release lock on panic or server error, save response otherwise
+			ctx := context.WithoutCancel(r.Context())
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+
+				errRelease := store.Release(ctx, key, lockToken)
+				if errRelease != nil {
+					slog.Default().Error("idempotency release", slog.Any("error", errRelease))
+				}
+			}()
+
+			next(iw, r, p)
+
+			if iw.status == 0 || iw.status >= http.StatusInternalServerError {
+				return
+			}
+
+			err = store.Complete(ctx, key, lockToken, entities.IdempotencyResponse{
+				Status:  iw.status,
+				Headers: entities.IdempotencyHeaders(iw.headers),
+				Body:    iw.body.Bytes(),
+			})
+			if errors.Is(err, ErrIdempotencyLockLost) {
+				// key belongs to other request now
+				slog.Default().Warn("idempotency complete", slog.Any("error", err))
+				completed = true
+				return
+			}
+
+			if err != nil {
+				slog.Default().Error("idempotency complete", slog.Any("error", err))
+				return
+			}
+
+			completed = true
+		}
+	}
+}
+
+func (w *idempotencyWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		w.headers = make(http.Header, len(w.Header()))
+		for name, values := range w.Header() {
+			if _, ok := idempotencySkipHeaders[name]; ok {
+				continue
+			}
+
+			w.headers[name] = append([]string(nil), values...)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.body.Write(data)
+
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap used by http.ResponseController
+func (w *idempotencyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func idempotencyReplay(w http.ResponseWriter, saved *entities.IdempotencyResponse) {
+	h := w.Header()
+	for name, values := range saved.Headers {
+		h[name] = values
+	}
+
+	h.Set(HeaderIdempotentReplayed, "true")
+	h.Set("Content-Length", strconv.Itoa(len(saved.Body)))
+
+	w.WriteHeader(saved.Status)
+	_, _ = w.Write(saved.Body)
+}
+
+func idempotencyScope(r *http.Request, route string, key string) string {
+	owner := "anonymous"
+	if sess := ctxsess.Get(r.Context()); sess != nil {
+		owner = strconv.FormatInt(sess.UserID, 10)
+	}
+
+	return strings.Join([]string{r.Method, route, owner, key}, ":")
+}
+
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n", r.Method, r.URL.RequestURI())
+	_, _ = h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
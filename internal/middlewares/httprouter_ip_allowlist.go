@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// IPAllowlist allow requests from listed networks only, empty list allows any.
+// Connection remote address is checked, proxy headers are not trusted
+func IPAllowlist(
+	allowed []netip.Prefix,
+	forbiddenFunc func(w http.ResponseWriter, message string) error,
+) HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		if len(allowed) == 0 {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			if !ipAllowed(r.RemoteAddr, allowed) {
+				err := forbiddenFunc(w, "")
+				if err != nil {
+					slog.Default().Error("write forbidden", slog.Any("error", err))
+				}
+
+				return
+			}
+
+			next(w, r, p)
+		}
+	}
+}
+
+// ParseIPAllowlist IPs and CIDRs, e.g. 10.0.0.1, 10.0.0.0/8
+func ParseIPAllowlist(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+
+	for _, item := range list {
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("parse ip %q: %w", item, err)
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("parse cidr %q: %w", item, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+func ipAllowed(remoteAddr string, allowed []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	// ipv4-mapped ipv6 matches ipv4 networks
+	addr = addr.Unmap()
+
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/andrdru/go-template/internal/ctxreqid"
+	"github.com/andrdru/go-template/internal/metrics"
+	"github.com/julienschmidt/httprouter"
+)
+
+// HTTPMetrics record RED metrics and write access log
+// route is a httprouter path template, e.g. /user/:id
+// sampling is a share of logged requests in [0, 1], server errors are logged always
+func HTTPMetrics(logger *slog.Logger, route string, sampling float64) HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			start := time.Now()
+
+			inFlight := metrics.GaugeHTTPInFlight(route, r.Method)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			rw := newResponseWriter(w)
+			next(rw, r, p)
+
+			duration := time.Since(start)
+			status := rw.Status()
+
+			metrics.ObserveHTTP(route, r.Method, statusClass(status), duration.Seconds(), rw.size)
+
+			if status < http.StatusInternalServerError && rand.Float64() >= sampling {
+				return
+			}
+
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "http request",
+				slog.String("request_id", ctxreqid.Get(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("duration", duration),
+				slog.Int("size", rw.size),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		}
+	}
+}
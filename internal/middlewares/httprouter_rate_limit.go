@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/andrdru/go-template/internal/ctxsess"
+	"github.com/andrdru/go-template/internal/metrics"
+	"github.com/andrdru/go-template/internal/ratelimit"
+	"github.com/julienschmidt/httprouter"
+)
+
+type rateLimiter interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+}
+
+const (
+	// HeaderRealIP .
+	HeaderRealIP = "X-Real-IP"
+	// HeaderAPIKey .
+	HeaderAPIKey = "X-API-Key"
+
+	rateLimitKeyIP     = "ip"
+	rateLimitKeyUser   = "user"
+	rateLimitKeyAPIKey = "api_key"
+)
+
+// RateLimit limit requests to route "METHOD /route/:template" with token bucket
+// key user requires SessionValidate before, falls back to ip for anonymous requests.
+// key api_key is used only for one of apiKeys, falls back to ip for unknown keys.
+// X-Real-IP is used as client ip only if sent by one of trustedProxies.
+// limiter errors are logged and request passes
+func RateLimit(
+	limiter rateLimiter,
+	route string,
+	rule configs.RateLimitRule,
+	trustedProxies []netip.Prefix,
+	apiKeys []string,
+	tooManyFunc func(w http.ResponseWriter, message string) error,
+) HTTPMiddleware {
+	limit := ratelimit.Limit{
+		Requests: rule.Requests,
+		Period:   rule.Period,
+		Burst:    rule.Burst,
+	}
+
+	apiKeySums := make([][]byte, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		sum := sha256.Sum256([]byte(apiKey))
+		apiKeySums = append(apiKeySums, sum[:])
+	}
+
+	return func(next httprouter.Handle) httprouter.Handle {
+		if limit.Requests <= 0 || limit.Period <= 0 {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			key := route + ":" + rateLimitKey(r, rule.Key, trustedProxies, apiKeySums)
+
+			res, err := limiter.Take(r.Context(), key, limit)
+			if err != nil {
+				metrics.CounterRateLimit(route, "error").Inc()
+				slog.Default().Error("rate limit", slog.Any("error", err))
+
+				next(w, r, p)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				metrics.CounterRateLimit(route, "limited").Inc()
+
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+
+				err = tooManyFunc(w, "")
+				if err != nil {
+					slog.Default().Error("write too many requests", slog.Any("error", err))
+				}
+
+				return
+			}
+
+			metrics.CounterRateLimit(route, "allowed").Inc()
+
+			next(w, r, p)
+		}
+	}
+}
+
+func rateLimitKey(r *http.Request, keyType string, trustedProxies []netip.Prefix, apiKeySums [][]byte) string {
+	switch keyType {
+	case rateLimitKeyUser:
+		if sess := ctxsess.Get(r.Context()); sess != nil {
+			return rateLimitKeyUser + ":" + strconv.FormatInt(sess.UserID, 10)
+		}
+	case rateLimitKeyAPIKey:
+		if apiKey := r.Header.Get(HeaderAPIKey); apiKey != "" {
+			// do not store raw keys in limiter storage
+			sum := sha256.Sum256([]byte(apiKey))
+			// random keys must not get fresh buckets
+			if apiKeyValid(sum[:], apiKeySums) {
+				return rateLimitKeyAPIKey + ":" + hex.EncodeToString(sum[:])
+			}
+		}
+	}
+
+	return rateLimitKeyIP + ":" + clientIP(r, trustedProxies)
+}
+
+// apiKeyValid sum is one of configured keys sums
+func apiKeyValid(sum []byte, apiKeySums [][]byte) bool {
+	valid := false
+	for _, apiKeySum := range apiKeySums {
+		// check all keys, constant time
+		if subtle.ConstantTimeCompare(sum, apiKeySum) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// clientIP X-Real-IP if request is sent by trusted proxy, remote address otherwise
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if ip := r.Header.Get(HeaderRealIP); ip != "" && ipAllowed(r.RemoteAddr, trustedProxies) {
+		return ip
+	}
+
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
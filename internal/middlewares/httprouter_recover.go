@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Recover handle panic of next handler with panicFunc
+// same signature as httprouter.Router.PanicHandler
+func Recover(panicFunc func(w http.ResponseWriter, r *http.Request, rcv any)) HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			defer func() {
+				rcv := recover()
+				if rcv == nil {
+					return
+				}
+
+				// let net/http abort response silently
+				if rcv == http.ErrAbortHandler {
+					panic(rcv)
+				}
+
+				panicFunc(w, r, rcv)
+			}()
+
+			next(w, r, p)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/andrdru/go-template/internal/ctxreqid"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// HeaderRequestID .
+	HeaderRequestID = "X-Request-ID"
+
+	requestIDMaxLen = 128
+)
+
+// RequestID take request id from header or generate new one
+// put it to context and response header
+func RequestID() HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			requestID := r.Header.Get(HeaderRequestID)
+			if requestID == "" || len(requestID) > requestIDMaxLen {
+				requestID = uuid.NewString()
+			}
+
+			w.Header().Set(HeaderRequestID, requestID)
+
+			next(w, r.WithContext(ctxreqid.Set(r.Context(), requestID)), p)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/andrdru/go-template/internal/configs"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SecurityHeaders add security response headers
+// X-Content-Type-Options is always sent, others if configured
+func SecurityHeaders(conf configs.SecurityHeaders) HTTPMiddleware {
+	headers := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+	}
+
+	if conf.HSTSMaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", conf.HSTSMaxAge)
+		if conf.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+
+		headers["Strict-Transport-Security"] = hsts
+	}
+
+	if conf.FrameOptions != "" {
+		headers["X-Frame-Options"] = conf.FrameOptions
+	}
+
+	if conf.ContentSecurityPolicy != "" {
+		headers["Content-Security-Policy"] = conf.ContentSecurityPolicy
+	}
+
+	if conf.ReferrerPolicy != "" {
+		headers["Referrer-Policy"] = conf.ReferrerPolicy
+	}
+
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			h := w.Header()
+			for key, value := range headers {
+				h.Set(key, value)
+			}
+
+			next(w, r, p)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/andrdru/go-template/internal/ctxreqid"
+	"github.com/andrdru/go-template/internal/metrics"
+	"github.com/julienschmidt/httprouter"
+)
+
+// timeoutWriter buffer response until handler finished in time
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	buf      bytes.Buffer
+	timedOut bool
+}
+
+// HandlerPanic panic of handler run by Timeout, Stack is of handler goroutine
+type HandlerPanic struct {
+	Value any
+	Stack []byte
+}
+
+// ErrHandlerTimeout write after timeout
+var ErrHandlerTimeout = errors.New("handler timeout")
+
+// Timeout set request context deadline, respond with timeoutFunc if handler has not finished.
+// Like http.TimeoutHandler response is buffered, so streaming routes should not use it.
+// Handler panic is passed to the calling goroutine as HandlerPanic,
+// panic after timeout response is logged
+func Timeout(timeout time.Duration, timeoutFunc func(w http.ResponseWriter, message string) error) HTTPMiddleware {
+	return func(next httprouter.Handle) httprouter.Handle {
+		if timeout <= 0 {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{
+				header: make(http.Header),
+			}
+
+			done := make(chan struct{})
+			panicChan := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					rcv := recover()
+					if rcv == nil {
+						return
+					}
+
+					// let net/http abort response silently
+					if rcv != http.ErrAbortHandler {
+						rcv = HandlerPanic{Value: rcv, Stack: debug.Stack()}
+					}
+
+					// sent under lock: timeout branch drains it after timedOut is set
+					tw.mu.Lock()
+					defer tw.mu.Unlock()
+
+					if tw.timedOut {
+						timeoutPanic(r, rcv)
+						return
+					}
+
+					panicChan <- rcv
+				}()
+
+				next(tw, r.WithContext(ctx), p)
+				close(done)
+			}()
+
+			select {
+			case rcv := <-panicChan:
+				panic(rcv)
+
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				h := w.Header()
+				for key, values := range tw.header {
+					h[key] = values
+				}
+
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+
+				w.WriteHeader(tw.status)
+				_, _ = w.Write(tw.buf.Bytes())
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+
+				// panicked at deadline
+				select {
+				case rcv := <-panicChan:
+					timeoutPanic(r, rcv)
+				default:
+				}
+
+				// client is gone, nothing to respond
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return
+				}
+
+				err := timeoutFunc(w, "")
+				if err != nil {
+					slog.Default().Error("write timeout", slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
+
+func (p HandlerPanic) String() string {
+	return fmt.Sprint(p.Value)
+}
+
+// timeoutPanic log panic of handler after timeout response
+func timeoutPanic(r *http.Request, rcv any) {
+	if rcv == http.ErrAbortHandler {
+		return
+	}
+
+	metrics.CounterPanics("http").Inc()
+
+	attrs := []any{
+		slog.String("request_id", ctxreqid.Get(r.Context())),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+	}
+
+	if p, ok := rcv.(HandlerPanic); ok {
+		attrs = append(attrs, slog.Any("error", p.Value), slog.String("stack", string(p.Stack)))
+	}
+
+	slog.Default().Error("panic after timeout", attrs...)
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut || w.status != 0 {
+		return
+	}
+
+	w.status = code
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return 0, ErrHandlerTimeout
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.buf.Write(data)
+}
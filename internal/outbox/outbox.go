@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/repos"
+)
+
+type (
+	// Outbox domain events written atomically with business data,
+	// delivered by Relay after transaction commit
+	Outbox struct {
+		repo *repos.Outbox
+	}
+
+	// Purge payload of JobPurge
+	Purge struct {
+		Before time.Time `json:"before"`
+	}
+)
+
+const (
+	// JobPurge delete events delivered or dead before, payload is Purge
+	JobPurge = "outbox.purge"
+)
+
+var (
+	ErrEventInvalid = errors.New("outbox event invalid")
+)
+
+// NewOutbox .
+func NewOutbox(repo *repos.Outbox) *Outbox {
+	return &Outbox{
+		repo: repo,
+	}
+}
+
+// Enqueue save event within transaction of context,
+// event is dropped on rollback and delivered at least once after commit.
+// Out of transaction event is saved immediately
+func (o *Outbox) Enqueue(ctx context.Context, event entities.OutboxEvent) error {
+	if event.Topic == "" || event.Name == "" {
+		return fmt.Errorf("%w: topic and name are required", ErrEventInvalid)
+	}
+
+	if !json.Valid(event.Payload) {
+		return fmt.Errorf("%w: payload is not json", ErrEventInvalid)
+	}
+
+	_, err := o.repo.EnqueueOutbox(ctx, event)
+	if err != nil {
+		return fmt.Errorf("enqueue: %w", err)
+	}
+
+	return nil
+}
+
+// Purge delete events delivered or dead before, handler of JobPurge
+func (o *Outbox) Purge(ctx context.Context, payload Purge) error {
+	count, err := o.repo.PurgeOutbox(ctx, payload.Before)
+	if err != nil {
+		return fmt.Errorf("purge outbox: %w", err)
+	}
+
+	slog.Default().Info("outbox purged", slog.Int64("count", count))
+
+	return nil
+}
+
+// NewEvent event with payload marshaled to json
+func NewEvent(topic string, name string, key string, payload any) (entities.OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return entities.OutboxEvent{}, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	return entities.OutboxEvent{
+		Topic:   topic,
+		Name:    name,
+		Key:     key,
+		Payload: data,
+	}, nil
+}
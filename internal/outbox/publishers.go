@@ -0,0 +1,225 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andrdru/go-template/internal/entities"
+)
+
+type (
+	// LogPublisher write events to log, e.g. for local development
+	LogPublisher struct {
+		logger *slog.Logger
+	}
+
+	// Webhook POST event as json to url
+	Webhook struct {
+		url    string
+		secret []byte
+		client *http.Client
+	}
+
+	// RedisStream append event to redis stream named by prefix and event topic
+	RedisStream struct {
+		client streamAdder
+		prefix string
+		maxLen int64
+	}
+
+	streamAdder interface {
+		XAdd(ctx context.Context, stream string, maxLen int64, fieldsAndValues ...any) (id string, err error)
+	}
+
+	webhookBody struct {
+		ID        int64           `json:"id"`
+		CreatedAt time.Time       `json:"created_at"`
+		Topic     string          `json:"topic"`
+		Name      string          `json:"name"`
+		Key       string          `json:"key,omitempty"`
+		Payload   json.RawMessage `json:"payload"`
+	}
+
+	webhookOptions struct {
+		secret string
+		client *http.Client
+	}
+
+	WebhookOption func(*webhookOptions)
+
+	redisStreamOptions struct {
+		prefix string
+		maxLen int64
+	}
+
+	RedisStreamOption func(*redisStreamOptions)
+)
+
+const (
+	// HeaderEventID consumers dedupe redelivered events by it
+	HeaderEventID = "X-Outbox-Event-ID"
+	// HeaderSignature "sha256=" hex HMAC of body with webhook secret
+	HeaderSignature = "X-Outbox-Signature"
+)
+
+var (
+	_ Publisher = &LogPublisher{}
+	_ Publisher = &Webhook{}
+	_ Publisher = &RedisStream{}
+)
+
+// NewLogPublisher .
+func NewLogPublisher(logger *slog.Logger) *LogPublisher {
+	return &LogPublisher{
+		logger: logger,
+	}
+}
+
+// Publish .
+func (p *LogPublisher) Publish(_ context.Context, event entities.OutboxEvent) error {
+	p.logger.Info("outbox event",
+		slog.Int64("id", event.ID),
+		slog.String("topic", event.Topic),
+		slog.String("name", event.Name),
+		slog.String("key", event.Key),
+		slog.String("payload", string(event.Payload)),
+	)
+
+	return nil
+}
+
+// NewWebhook .
+func NewWebhook(url string, opts ...WebhookOption) *Webhook {
+	args := &webhookOptions{
+		client: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	w := &Webhook{
+		url:    url,
+		client: args.client,
+	}
+
+	if args.secret != "" {
+		w.secret = []byte(args.secret)
+	}
+
+	return w
+}
+
+// Publish non 2xx response is an error
+func (w *Webhook) Publish(ctx context.Context, event entities.OutboxEvent) error {
+	body, err := json.Marshal(webhookBody{
+		ID:        event.ID,
+		CreatedAt: event.CreatedAt,
+		Topic:     event.Topic,
+		Name:      event.Name,
+		Key:       event.Key,
+		Payload:   event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, strconv.FormatInt(event.ID, 10))
+
+	if w.secret != nil {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(HeaderSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer func() {
+		// drain to reuse connection
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// NewRedisStream .
+func NewRedisStream(client streamAdder, opts ...RedisStreamOption) *RedisStream {
+	args := &redisStreamOptions{}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	return &RedisStream{
+		client: client,
+		prefix: args.prefix,
+		maxLen: args.maxLen,
+	}
+}
+
+// Publish .
+func (s *RedisStream) Publish(ctx context.Context, event entities.OutboxEvent) error {
+	_, err := s.client.XAdd(ctx, s.prefix+event.Topic, s.maxLen,
+		"id", event.ID,
+		"created_at", event.CreatedAt.Format(time.RFC3339Nano),
+		"name", event.Name,
+		"key", event.Key,
+		"payload", event.Payload,
+	)
+	if err != nil {
+		return fmt.Errorf("xadd: %w", err)
+	}
+
+	return nil
+}
+
+// WithWebhookSecret sign body, see HeaderSignature
+func WithWebhookSecret(secret string) WebhookOption {
+	return func(args *webhookOptions) {
+		args.secret = secret
+	}
+}
+
+// WithWebhookClient .
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(args *webhookOptions) {
+		args.client = client
+	}
+}
+
+// WithStreamPrefix stream name prefix, e.g. "events:"
+func WithStreamPrefix(prefix string) RedisStreamOption {
+	return func(args *redisStreamOptions) {
+		args.prefix = prefix
+	}
+}
+
+// WithStreamMaxLen approximate stream length limit, zero keeps all entries
+func WithStreamMaxLen(maxLen int64) RedisStreamOption {
+	return func(args *redisStreamOptions) {
+		args.maxLen = maxLen
+	}
+}
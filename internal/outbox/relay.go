@@ -0,0 +1,327 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/andrdru/go-template/internal/entities"
+	"github.com/andrdru/go-template/internal/metrics"
+	"github.com/andrdru/go-template/internal/repos"
+)
+
+type (
+	// Relay deliver outbox events to publisher.
+	// Due events are claimed for lease in short statement, so relays of app replicas share the load,
+	// events of crashed relay are claimed by other one after lease. Events are published out of transaction,
+	// result of each one is saved by own statement. Delivery is at least once: event is published again
+	// if its status was not saved, consumers should dedupe by event id.
+	// Order is not guaranteed between retries and relays
+	Relay struct {
+		logger    *slog.Logger
+		repo      *repos.Outbox
+		publisher Publisher
+
+		pollInterval   time.Duration
+		batchSize      int
+		maxAttempts    int
+		backoffBase    time.Duration
+		backoffMax     time.Duration
+		publishTimeout time.Duration
+		lease          time.Duration
+
+		// ctx of publishing, canceled if Stop deadline is exceeded
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		stop     chan struct{}
+		stopOnce sync.Once
+		done     chan struct{}
+	}
+
+	// Publisher deliver event to external system
+	Publisher interface {
+		Publish(ctx context.Context, event entities.OutboxEvent) error
+	}
+
+	relayOptions struct {
+		pollInterval   time.Duration
+		batchSize      int
+		maxAttempts    int
+		backoffBase    time.Duration
+		backoffMax     time.Duration
+		publishTimeout time.Duration
+		lease          time.Duration
+	}
+
+	RelayOption func(*relayOptions)
+)
+
+var (
+	// PollIntervalDefault pause after batch shorter than batch size
+	PollIntervalDefault = time.Second
+	// BatchSizeDefault events claimed at once
+	BatchSizeDefault = 100
+	// MaxAttemptsDefault delivery attempts before event goes to dead letters
+	MaxAttemptsDefault = 10
+	// BackoffBaseDefault delay after first failed attempt, doubled for every next one
+	BackoffBaseDefault = time.Second
+	// BackoffMaxDefault .
+	BackoffMaxDefault = time.Hour
+	// PublishTimeoutDefault single event delivery deadline
+	PublishTimeoutDefault = 5 * time.Second
+	// LeaseDefault claimed batch delivery deadline, rest of batch is claimed again after
+	LeaseDefault = time.Minute
+
+	// statusTimeout saving delivery result deadline
+	statusTimeout = 5 * time.Second
+)
+
+// NewRelay .
+func NewRelay(logger *slog.Logger, repo *repos.Outbox, publisher Publisher, opts ...RelayOption) *Relay {
+	args := &relayOptions{
+		pollInterval:   PollIntervalDefault,
+		batchSize:      BatchSizeDefault,
+		maxAttempts:    MaxAttemptsDefault,
+		backoffBase:    BackoffBaseDefault,
+		backoffMax:     BackoffMaxDefault,
+		publishTimeout: PublishTimeoutDefault,
+		lease:          LeaseDefault,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	// lease fits one publish at least
+	if args.lease <= args.publishTimeout {
+		args.lease = 2 * args.publishTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Relay{
+		logger:         logger,
+		repo:           repo,
+		publisher:      publisher,
+		pollInterval:   args.pollInterval,
+		batchSize:      args.batchSize,
+		maxAttempts:    args.maxAttempts,
+		backoffBase:    args.backoffBase,
+		backoffMax:     args.backoffMax,
+		publishTimeout: args.publishTimeout,
+		lease:          args.lease,
+		ctx:            ctx,
+		cancel:         cancel,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+}
+
+// Run deliver events until Stop
+func (r *Relay) Run() {
+	defer close(r.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-timer.C:
+		}
+
+		count, err := r.relay()
+		if err != nil {
+			r.logger.Error("outbox relay", slog.Any("error", err))
+		}
+
+		// full batch: more events are probably due
+		wait := r.pollInterval
+		if err == nil && count == r.batchSize {
+			wait = 0
+		}
+
+		timer.Reset(wait)
+	}
+}
+
+// Stop polling, wait for event in progress, rest of batch is released.
+// Publishing is canceled if ctx is done first
+func (r *Relay) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+
+	select {
+	case <-r.done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		return ctx.Err()
+	}
+}
+
+// relay claim and deliver batch of due events, returns claimed count
+func (r *Relay) relay() (count int, err error) {
+	events, err := r.repo.ClaimOutbox(r.ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim events: %w", err)
+	}
+
+	// publish is not started if it may outlive lease
+	deadline := time.Now().Add(r.lease - r.publishTimeout)
+
+	for i, event := range events {
+		if r.stopped() || time.Now().After(deadline) {
+			r.release(events[i:])
+			break
+		}
+
+		r.deliver(event)
+	}
+
+	return len(events), nil
+}
+
+// deliver publish event and save result
+func (r *Relay) deliver(event entities.OutboxEvent) {
+	ctxPublish, cancel := context.WithTimeout(r.ctx, r.publishTimeout)
+	errPublish := r.publisher.Publish(ctxPublish, event)
+	cancel()
+
+	// result is saved on shutdown too
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.ctx), statusTimeout)
+	defer cancel()
+
+	var err error
+	attempt := event.Attempts + 1
+
+	switch {
+	case errPublish == nil:
+		metrics.CounterOutboxEvents(event.Name, "delivered").Inc()
+		err = r.repo.DeliveredOutbox(ctx, event.ID)
+
+	case attempt >= r.maxAttempts:
+		metrics.CounterOutboxEvents(event.Name, "dead").Inc()
+
+		r.logger.Error("outbox event is dead",
+			slog.Int64("id", event.ID),
+			slog.String("topic", event.Topic),
+			slog.String("name", event.Name),
+			slog.Int("attempts", attempt),
+			slog.Any("error", errPublish),
+		)
+
+		err = r.repo.DeadOutbox(ctx, event.ID, errPublish.Error())
+
+	default:
+		metrics.CounterOutboxEvents(event.Name, "retry").Inc()
+
+		r.logger.Warn("outbox event delivery failed",
+			slog.Int64("id", event.ID),
+			slog.String("topic", event.Topic),
+			slog.String("name", event.Name),
+			slog.Int("attempt", attempt),
+			slog.Any("error", errPublish),
+		)
+
+		nextAttemptAt := time.Now().Add(backoff(attempt, r.backoffBase, r.backoffMax))
+		err = r.repo.RetryOutbox(ctx, event.ID, nextAttemptAt, errPublish.Error())
+	}
+
+	if err != nil {
+		// event is delivered again after lease
+		r.logger.Error("save outbox event result", slog.Int64("id", event.ID), slog.Any("error", err))
+	}
+}
+
+// release lease of events not delivered, so other relay takes them without waiting
+func (r *Relay) release(events []entities.OutboxEvent) {
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.ctx), statusTimeout)
+	defer cancel()
+
+	if err := r.repo.ReleaseOutbox(ctx, ids); err != nil {
+		r.logger.Error("release outbox events", slog.Int("count", len(ids)), slog.Any("error", err))
+	}
+}
+
+func (r *Relay) stopped() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff exponential delay of attempt, half of it is jittered
+func backoff(attempt int, base time.Duration, maxDelay time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+
+	if d > maxDelay {
+		d = maxDelay
+	}
+
+	if d <= 1 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// WithPollInterval .
+func WithPollInterval(interval time.Duration) RelayOption {
+	return func(args *relayOptions) {
+		args.pollInterval = interval
+	}
+}
+
+// WithBatchSize .
+func WithBatchSize(size int) RelayOption {
+	return func(args *relayOptions) {
+		args.batchSize = size
+	}
+}
+
+// WithMaxAttempts event goes to dead letters after maxAttempts failed deliveries
+func WithMaxAttempts(maxAttempts int) RelayOption {
+	return func(args *relayOptions) {
+		args.maxAttempts = maxAttempts
+	}
+}
+
+// WithBackoff exponential delay between delivery attempts
+func WithBackoff(base time.Duration, maxDelay time.Duration) RelayOption {
+	return func(args *relayOptions) {
+		args.backoffBase = base
+		args.backoffMax = maxDelay
+	}
+}
+
+// WithPublishTimeout .
+func WithPublishTimeout(timeout time.Duration) RelayOption {
+	return func(args *relayOptions) {
+		args.publishTimeout = timeout
+	}
+}
+
+// WithLease claimed batch delivery deadline, should be longer than publish timeout
+func WithLease(lease time.Duration) RelayOption {
+	return func(args *relayOptions) {
+		args.lease = lease
+	}
+}
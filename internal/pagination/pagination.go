@@ -0,0 +1,324 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Paginator parse list params and sign cursors
+	Paginator struct {
+		secret []byte
+	}
+
+	// Spec list endpoint allowed params
+	Spec struct {
+		DefaultLimit int
+		MaxLimit     int
+		// DefaultSort field name, "-" prefix for descending
+		DefaultSort string
+		// SortFields allowlist
+		SortFields []string
+		// Filters allowed by field
+		Filters map[string]FilterSpec
+	}
+
+	// FilterSpec allowed operators and value type of filter field
+	FilterSpec struct {
+		Type FilterType
+		Ops  []Op
+	}
+
+	// Params parsed list request
+	Params struct {
+		Limit   int
+		Sort    string
+		Desc    bool
+		Cursor  *Cursor
+		Filters []Filter
+	}
+
+	// Cursor keyset position: sort value and id of last returned item.
+	// Bound to sort and filters of the list it was issued for
+	Cursor struct {
+		Sort string `json:"s"`
+		Desc bool   `json:"d"`
+		// Filters hash of params filters
+		Filters string `json:"f"`
+		Value   string `json:"v"`
+		ID      int64  `json:"i"`
+	}
+
+	Filter struct {
+		Field string
+		Op    Op
+		// Values parsed by FilterType: string, int64, time.Time or bool
+		Values []any
+	}
+
+	Op string
+
+	FilterType int
+
+	// FieldError invalid query param
+	FieldError struct {
+		Field   string
+		Message string
+	}
+)
+
+const (
+	OpEq  Op = "eq"
+	OpNe  Op = "ne"
+	OpGt  Op = "gt"
+	OpGte Op = "gte"
+	OpLt  Op = "lt"
+	OpLte Op = "lte"
+	// OpIn comma separated values
+	OpIn Op = "in"
+
+	paramLimit  = "limit"
+	paramSort   = "sort"
+	paramCursor = "cursor"
+
+	// MaxFilterValues max values of OpIn filter
+	MaxFilterValues = 100
+)
+
+const (
+	FilterString FilterType = iota
+	FilterInt
+	// FilterTime RFC 3339 time or date
+	FilterTime
+	FilterBool
+)
+
+var (
+	ErrCursorInvalid = errors.New("cursor invalid")
+)
+
+// NewPaginator secret signs cursors, so clients can not forge them
+func NewPaginator(secret string) *Paginator {
+	return &Paginator{
+		secret: []byte(secret),
+	}
+}
+
+// Parse list params from query
+// ?limit=20&sort=-created_at&cursor=...&email=a@b.c&created_at[gte]=2023-01-01
+func (p *Paginator) Parse(query url.Values, spec Spec) (params Params, err error) {
+	params.Limit = spec.DefaultLimit
+	if v := query.Get(paramLimit); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil || params.Limit <= 0 {
+			return Params{}, &FieldError{Field: paramLimit, Message: "should be positive integer"}
+		}
+	}
+
+	if spec.MaxLimit > 0 && params.Limit > spec.MaxLimit {
+		params.Limit = spec.MaxLimit
+	}
+
+	sortParam := query.Get(paramSort)
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+
+	params.Sort, params.Desc = strings.TrimPrefix(sortParam, "-"), strings.HasPrefix(sortParam, "-")
+	if !slices.Contains(spec.SortFields, params.Sort) {
+		return Params{}, &FieldError{Field: paramSort, Message: fmt.Sprintf("one of: %s", strings.Join(spec.SortFields, ", "))}
+	}
+
+	params.Filters, err = parseFilters(query, spec.Filters)
+	if err != nil {
+		return Params{}, err
+	}
+
+	if v := query.Get(paramCursor); v != "" {
+		params.Cursor, err = p.decode(v)
+		if err != nil ||
+			params.Cursor.Sort != params.Sort ||
+			params.Cursor.Desc != params.Desc ||
+			params.Cursor.Filters != filtersHash(params.Filters) {
+			return Params{}, &FieldError{Field: paramCursor, Message: ErrCursorInvalid.Error()}
+		}
+	}
+
+	return params, nil
+}
+
+// Next cursor after last returned item
+func (p *Paginator) Next(params Params, value string, id int64) string {
+	return p.encode(Cursor{
+		Sort:    params.Sort,
+		Desc:    params.Desc,
+		Filters: filtersHash(params.Filters),
+		Value:   value,
+		ID:      id,
+	})
+}
+
+// encode base64(json).base64(hmac)
+func (p *Paginator) encode(cursor Cursor) string {
+	// marshal of plain struct does not fail
+	payload, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(p.sign(payload))
+}
+
+func (p *Paginator) decode(s string) (*Cursor, error) {
+	payloadPart, signPart, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, ErrCursorInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", ErrCursorInvalid)
+	}
+
+	sign, err := base64.RawURLEncoding.DecodeString(signPart)
+	if err != nil {
+		return nil, fmt.Errorf("decode sign: %w", ErrCursorInvalid)
+	}
+
+	if !hmac.Equal(sign, p.sign(payload)) {
+		return nil, fmt.Errorf("sign mismatch: %w", ErrCursorInvalid)
+	}
+
+	cursor := &Cursor{}
+	err = json.Unmarshal(payload, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", ErrCursorInvalid)
+	}
+
+	return cursor, nil
+}
+
+func (p *Paginator) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
+
+// filtersHash cursor of other filters is rejected, filters are sorted by parseFilters
+func filtersHash(filters []Filter) string {
+	h := sha256.New()
+	for _, filter := range filters {
+		// values are string, int64, time.Time or bool, marshal does not fail
+		values, _ := json.Marshal(filter.Values)
+		_, _ = fmt.Fprintf(h, "%s\n%s\n%s\n", filter.Field, filter.Op, values)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+// parseFilters field=value is eq, field[op]=value otherwise
+func parseFilters(query url.Values, allowed map[string]FilterSpec) (filters []Filter, err error) {
+	// stable filters order, same query gives same sql
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := query[key]
+		if key == paramLimit || key == paramSort || key == paramCursor || len(values) == 0 {
+			continue
+		}
+
+		field, op := key, OpEq
+		if name, rest, ok := strings.Cut(key, "["); ok && strings.HasSuffix(rest, "]") {
+			field, op = name, Op(strings.TrimSuffix(rest, "]"))
+		}
+
+		spec, ok := allowed[field]
+		if !ok {
+			continue
+		}
+
+		if !slices.Contains(spec.Ops, op) {
+			return nil, &FieldError{Field: key, Message: fmt.Sprintf("operator %s is not allowed", op)}
+		}
+
+		raw := []string{values[0]}
+		if op == OpIn {
+			raw = strings.Split(values[0], ",")
+			if len(raw) > MaxFilterValues {
+				return nil, &FieldError{Field: key, Message: fmt.Sprintf("max %d values", MaxFilterValues)}
+			}
+		}
+
+		filter := Filter{
+			Field:  field,
+			Op:     op,
+			Values: make([]any, 0, len(raw)),
+		}
+
+		for _, v := range raw {
+			value, err := spec.Type.parse(v)
+			if err != nil {
+				return nil, &FieldError{Field: key, Message: err.Error()}
+			}
+
+			filter.Values = append(filter.Values, value)
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// parse filter value, error is client message
+func (t FilterType) parse(v string) (any, error) {
+	switch t {
+	case FilterInt:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errors.New("should be integer")
+		}
+
+		return i, nil
+
+	case FilterTime:
+		if tm, err := time.Parse(time.RFC3339, v); err == nil {
+			return tm, nil
+		}
+
+		tm, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return nil, errors.New("should be RFC 3339 time or date")
+		}
+
+		return tm, nil
+
+	case FilterBool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("should be boolean")
+		}
+
+		return b, nil
+
+	default:
+		return v, nil
+	}
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
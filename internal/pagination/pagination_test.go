@@ -0,0 +1,138 @@
+package pagination
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var testSpec = Spec{
+	DefaultLimit: 20,
+	MaxLimit:     100,
+	DefaultSort:  "-created_at",
+	SortFields:   []string{"id", "created_at"},
+	Filters: map[string]FilterSpec{
+		"email": {
+			Type: FilterString,
+			Ops:  []Op{OpEq, OpIn},
+		},
+		"created_at": {
+			Type: FilterTime,
+			Ops:  []Op{OpGte, OpLt},
+		},
+	},
+}
+
+// nextQuery query of first page with cursor of next page
+func nextQuery(t *testing.T, p *Paginator, query url.Values) url.Values {
+	t.Helper()
+
+	params, err := p.Parse(query, testSpec)
+	if err != nil {
+		t.Fatalf("Parse first page: %v", err)
+	}
+
+	next := url.Values{}
+	for key, values := range query {
+		next[key] = values
+	}
+
+	next.Set(paramCursor, p.Next(params, "2023-01-01T00:00:00Z", 42))
+
+	return next
+}
+
+func assertCursorInvalid(t *testing.T, err error) {
+	t.Helper()
+
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != paramCursor {
+		t.Fatalf("Parse: got %v, want cursor field error", err)
+	}
+}
+
+func TestPaginator_Cursor(t *testing.T) {
+	p := NewPaginator("secret")
+
+	query := url.Values{
+		"sort":            {"created_at"},
+		"email[in]":       {"a@b.c,d@e.f"},
+		"created_at[gte]": {"2023-01-01"},
+	}
+
+	params, err := p.Parse(nextQuery(t, p, query), testSpec)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := Cursor{
+		Sort:    "created_at",
+		Desc:    false,
+		Filters: filtersHash(params.Filters),
+		Value:   "2023-01-01T00:00:00Z",
+		ID:      42,
+	}
+
+	if params.Cursor == nil || *params.Cursor != want {
+		t.Fatalf("cursor: got %+v, want %+v", params.Cursor, want)
+	}
+}
+
+func TestPaginator_CursorTampered(t *testing.T) {
+	p := NewPaginator("secret")
+	next := nextQuery(t, p, url.Values{})
+	cursor := next.Get(paramCursor)
+
+	_, err := NewPaginator("other secret").Parse(next, testSpec)
+	assertCursorInvalid(t, err)
+
+	payload, sign, _ := strings.Cut(cursor, ".")
+
+	tests := map[string]string{
+		"payload changed": strings.ToUpper(payload) + "." + sign,
+		"sign changed":    payload + "." + strings.ToUpper(sign),
+		"no sign":         payload,
+		"not base64":      "!." + sign,
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			next.Set(paramCursor, value)
+
+			_, err := p.Parse(next, testSpec)
+			assertCursorInvalid(t, err)
+		})
+	}
+}
+
+func TestPaginator_CursorOtherList(t *testing.T) {
+	p := NewPaginator("secret")
+
+	query := url.Values{
+		"sort":  {"-created_at"},
+		"email": {"a@b.c"},
+	}
+
+	tests := map[string]func(q url.Values){
+		"sort field":     func(q url.Values) { q.Set("sort", "-id") },
+		"sort order":     func(q url.Values) { q.Set("sort", "created_at") },
+		"filter value":   func(q url.Values) { q.Set("email", "d@e.f") },
+		"filter added":   func(q url.Values) { q.Set("created_at[lt]", "2024-01-01") },
+		"filter removed": func(q url.Values) { q.Del("email") },
+		"filter op": func(q url.Values) {
+			q.Del("email")
+			q.Set("email[in]", "a@b.c")
+		},
+	}
+
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			next := nextQuery(t, p, query)
+			change(next)
+
+			_, err := p.Parse(next, testSpec)
+			assertCursorInvalid(t, err)
+		})
+	}
+}
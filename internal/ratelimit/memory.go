@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// Memory in-process store, state is not shared between app instances
+	Memory struct {
+		mu      sync.Mutex
+		buckets map[string]*bucket
+		sweepAt time.Time
+	}
+
+	bucket struct {
+		tokens float64
+		ts     time.Time
+		// full time when bucket refills, safe to forget after
+		full time.Time
+	}
+)
+
+// memorySweepInterval how often full buckets are dropped
+var memorySweepInterval = time.Minute
+
+var _ Store = &Memory{}
+
+// NewMemory .
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		sweepAt: time.Now().Add(memorySweepInterval),
+	}
+}
+
+// Take implement Store
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens: limit.capacity(),
+			ts:     now,
+		}
+		m.buckets[key] = b
+	}
+
+	b.tokens = limit.refill(b.tokens, now.Sub(b.ts))
+	b.ts = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	res := newResult(limit, b.tokens, allowed)
+	b.full = now.Add(res.Reset)
+
+	return res, nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	if now.Before(m.sweepAt) {
+		return
+	}
+
+	for key, b := range m.buckets {
+		if now.After(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+
+	m.sweepAt = now.Add(memorySweepInterval)
+}
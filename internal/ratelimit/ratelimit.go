@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+type (
+	// Store token bucket state storage
+	Store interface {
+		// Take one token from bucket by key
+		Take(ctx context.Context, key string, limit Limit) (Result, error)
+	}
+
+	// Limit token bucket params
+	// Requests per Period, up to Burst at once. Burst = Requests if not set
+	Limit struct {
+		Requests int
+		Period   time.Duration
+		Burst    int
+	}
+
+	// Result of Take
+	Result struct {
+		Allowed   bool
+		Limit     int
+		Remaining int
+		// Reset time until bucket is full
+		Reset time.Duration
+		// RetryAfter time until next token, zero if allowed
+		RetryAfter time.Duration
+	}
+)
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+
+	return float64(l.Requests)
+}
+
+// rate tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// refill tokens for elapsed time
+func (l Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return tokens
+	}
+
+	return math.Min(l.capacity(), tokens+elapsed.Seconds()*l.rate())
+}
+
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     int(limit.capacity()),
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((limit.capacity() - tokens) / limit.rate()),
+	}
+
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
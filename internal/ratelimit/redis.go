@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/andrdru/go-template/redis"
+	redigoRedis "github.com/gomodule/redigo/redis"
+)
+
+type (
+	// Redis store shared between app instances
+	Redis struct {
+		redis *redis.Redis
+	}
+)
+
+const (
+	redisKeyPrefix = "ratelimit:"
+)
+
+// takeScript atomic token bucket
+// KEYS[1] bucket key
+// ARGV: rate per ms, capacity, now ms, ttl ms
+// returns: allowed 0/1, tokens left as string to keep fraction
+var takeScript = redis.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], ttl)
+
+return {allowed, tostring(tokens)}
+`)
+
+var _ Store = &Redis{}
+
+// NewRedis .
+func NewRedis(r *redis.Redis) *Redis {
+	return &Redis{
+		redis: r,
+	}
+}
+
+// Take implement Store
+func (r *Redis) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	ratePerMs := limit.rate() / float64(time.Second/time.Millisecond)
+	ttl := seconds(limit.capacity() / limit.rate())
+
+	data, err := r.redis.Eval(takeScript,
+		redisKeyPrefix+key,
+		strconv.FormatFloat(ratePerMs, 'f', -1, 64),
+		strconv.FormatFloat(limit.capacity(), 'f', -1, 64),
+		time.Now().UnixMilli(),
+		ttl.Milliseconds()+1,
+	)
+	if err != nil {
+		return Result{}, fmt.Errorf("eval: %w", err)
+	}
+
+	values, err := redigoRedis.Values(data, nil)
+	if err != nil || len(values) != 2 {
+		return Result{}, fmt.Errorf("script result %v: %w", data, redis.ErrValueInvalidFormat)
+	}
+
+	allowed, err := redigoRedis.Int(values[0], nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("parse allowed: %w", err)
+	}
+
+	tokens, err := redigoRedis.Float64(values[1], nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("parse tokens: %w", err)
+	}
+
+	return newResult(limit, tokens, allowed == 1), nil
+}
@@ -2,7 +2,11 @@ package repos
 
 import (
 	"context"
+	"database/sql"
+	"log/slog"
+	"time"
 
+	"github.com/andrdru/go-template/internal/metrics"
 	"github.com/andrdru/go-template/tx"
 )
 
@@ -10,6 +14,47 @@ type (
 	// Transactor sql helper
 	transactor interface {
 		DB(ctx context.Context) (db tx.QueryExecutor)
+		WriteDB(ctx context.Context) (db tx.QueryExecutor)
+		ReadDB(ctx context.Context) (db tx.QueryExecutor)
 		TX(ctx context.Context, processor func(txCtx context.Context) error, opts ...tx.Option) error
 	}
+
+	// txObserver transactions metrics
+	txObserver struct{}
 )
+
+// NewTX transactor with metrics, shared by repos
+func NewTX(db *sql.DB, opts ...tx.TXOption) *tx.TX {
+	return tx.NewTX(db, append([]tx.TXOption{tx.WithObserver(txObserver{})}, opts...)...)
+}
+
+func (txObserver) TxRetry(label string, _ int, _ error) {
+	metrics.CounterTxRetries(txLabel(label), "retry").Inc()
+}
+
+func (txObserver) TxGiveUp(label string, attempts int, err error) {
+	metrics.CounterTxRetries(txLabel(label), "give_up").Inc()
+
+	slog.Default().Warn("tx retries exhausted",
+		slog.String("label", txLabel(label)),
+		slog.Int("attempts", attempts),
+		slog.Any("error", err),
+	)
+}
+
+func (txObserver) TxDone(label string, duration time.Duration, err error) {
+	result := "commit"
+	if err != nil {
+		result = "rollback"
+	}
+
+	metrics.HistogramTxDuration(txLabel(label), result).Observe(duration.Seconds())
+}
+
+func txLabel(label string) string {
+	if label == "" {
+		return "default"
+	}
+
+	return label
+}
@@ -0,0 +1,177 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrdru/go-template/internal/entities"
+)
+
+type Idempotency struct {
+	db transactor
+}
+
+func NewIdempotency(db transactor) *Idempotency {
+	return &Idempotency{
+		db: db,
+	}
+}
+
+// LockIdempotency insert key in progress owned by lockToken
+// existing key is taken over if created before expiredBefore,
+// or if its lock is stale and fingerprint is the same
+func (i *Idempotency) LockIdempotency(
+	ctx context.Context,
+	key string,
+	fingerprint string,
+	lockToken string,
+	lockedUntil time.Time,
+	expiredBefore time.Time,
+) (acquired bool, err error) {
+	const query = `-- name: idempotency_lock
+INSERT INTO idempotency_keys(key, fingerprint, lock_token, locked_until) VALUES($1, $2, $3, $4)
+ON CONFLICT (key) DO UPDATE SET created_at   = now(),
+                                updated_at   = now(),
+                                fingerprint  = EXCLUDED.fingerprint,
+                                lock_token   = EXCLUDED.lock_token,
+                                locked_until = EXCLUDED.locked_until,
+                                status       = NULL,
+                                headers      = NULL,
+                                body         = NULL
+WHERE idempotency_keys.created_at < $5
+   OR (idempotency_keys.status IS NULL
+    AND idempotency_keys.locked_until < now()
+    AND idempotency_keys.fingerprint = EXCLUDED.fingerprint)
+RETURNING id`
+
+	var id int64
+	err = i.db.DB(ctx).QueryRowContext(ctx, query, key, fingerprint, lockToken, lockedUntil, expiredBefore).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *Idempotency) Idempotency(ctx context.Context, key string) (item entities.Idempotency, err error) {
+	const query = `-- name: idempotency_get
+SELECT id,
+       created_at,
+       updated_at,
+       key,
+       fingerprint,
+       locked_until,
+       status,
+       headers,
+       body
+FROM idempotency_keys WHERE key = $1`
+
+	var (
+		status  sql.NullInt64
+		headers entities.IdempotencyHeaders
+		body    []byte
+	)
+
+	err = i.db.DB(ctx).QueryRowContext(ctx, query, key).Scan(
+		&item.ID,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.Key,
+		&item.Fingerprint,
+		&item.LockedUntil,
+		&status,
+		&headers,
+		&body,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.Idempotency{}, entities.ErrNotFound
+		}
+
+		return entities.Idempotency{}, err
+	}
+
+	if status.Valid {
+		item.Response = &entities.IdempotencyResponse{
+			Status:  int(status.Int64),
+			Headers: headers,
+			Body:    body,
+		}
+	}
+
+	return item, nil
+}
+
+// SaveIdempotencyResponse save response and release lock,
+// entities.ErrNotFound if lock is taken over by other request
+func (i *Idempotency) SaveIdempotencyResponse(
+	ctx context.Context,
+	key string,
+	lockToken string,
+	resp entities.IdempotencyResponse,
+) (err error) {
+	const query = `-- name: idempotency_save
+UPDATE idempotency_keys
+SET updated_at   = now(),
+    lock_token   = NULL,
+    locked_until = NULL,
+    status       = $3,
+    headers      = $4,
+    body         = $5
+WHERE key = $1 AND lock_token = $2 AND status IS NULL`
+
+	res, err := i.db.DB(ctx).ExecContext(ctx, query, key, lockToken, resp.Status, resp.Headers, resp.Body)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if count == 0 {
+		return entities.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteIdempotency delete key in progress, so request can be retried.
+// Key is kept if lock is taken over by other request
+func (i *Idempotency) DeleteIdempotency(ctx context.Context, key string, lockToken string) (err error) {
+	const query = `-- name: idempotency_delete
+DELETE FROM idempotency_keys WHERE key = $1 AND lock_token = $2 AND status IS NULL`
+
+	_, err = i.db.DB(ctx).ExecContext(ctx, query, key, lockToken)
+
+	return err
+}
+
+// PurgeIdempotency delete keys created before, keys in progress are kept
+func (i *Idempotency) PurgeIdempotency(ctx context.Context, before time.Time) (count int64, err error) {
+	const query = `-- name: idempotency_purge
+DELETE FROM idempotency_keys
+WHERE created_at < $1
+  AND (locked_until IS NULL OR locked_until < now())`
+
+	res, err := i.db.DB(ctx).ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	count, err = res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return count, nil
+}
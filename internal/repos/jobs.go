@@ -0,0 +1,183 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/andrdru/go-template/internal/entities"
+)
+
+type Jobs struct {
+	db transactor
+}
+
+func NewJobs(db transactor) *Jobs {
+	return &Jobs{
+		db: db,
+	}
+}
+
+// EnqueueJob insert job, within transaction of context if open
+// entities.ErrAlreadyExists if pending job with same name and unique key exists
+func (j *Jobs) EnqueueJob(ctx context.Context, job entities.Job) (id int64, err error) {
+	const query = `-- name: job_enqueue
+INSERT INTO jobs(name, payload, unique_key, max_attempts, run_at) VALUES($1, $2, $3, $4, $5)
+ON CONFLICT (name, unique_key) WHERE unique_key IS NOT NULL AND status = 'pending' DO NOTHING
+RETURNING id`
+
+	err = j.db.DB(ctx).QueryRowContext(ctx, query,
+		job.Name,
+		job.Payload,
+		job.UniqueKey,
+		job.MaxAttempts,
+		job.RunAt,
+	).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, entities.ErrAlreadyExists
+		}
+
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// ClaimJobs lock due jobs of names for lockFor and count attempt
+// jobs claimed by other workers are skipped until their lock expires
+func (j *Jobs) ClaimJobs(ctx context.Context, names []string, limit int, lockFor time.Duration) (jobs []entities.Job, err error) {
+	const query = `-- name: job_claim
+UPDATE jobs
+SET updated_at   = now(),
+    attempts     = attempts + 1,
+    locked_until = now() + $3 * interval '1 millisecond'
+WHERE id IN (SELECT id
+             FROM jobs
+             WHERE status = 'pending'
+               AND run_at <= now()
+               AND (locked_until IS NULL OR locked_until < now())
+               AND name = ANY ($1)
+             ORDER BY run_at, id
+             LIMIT $2 FOR UPDATE SKIP LOCKED)
+RETURNING id, created_at, name, payload, unique_key, attempts, max_attempts, run_at`
+
+	rows, err := j.db.DB(ctx).QueryContext(ctx, query, pq.Array(names), limit, lockFor.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	jobs = make([]entities.Job, 0, limit)
+	for rows.Next() {
+		var job entities.Job
+		err = rows.Scan(
+			&job.ID,
+			&job.CreatedAt,
+			&job.Name,
+			&job.Payload,
+			&job.UniqueKey,
+			&job.Attempts,
+			&job.MaxAttempts,
+			&job.RunAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// CompleteJob finish attempt successfully
+// entities.ErrNotFound if attempt lock expired and job was claimed again
+func (j *Jobs) CompleteJob(ctx context.Context, id int64, attempt int) (err error) {
+	const query = `-- name: job_complete
+UPDATE jobs
+SET updated_at   = now(),
+    status       = 'done',
+    locked_until = NULL,
+    finished_at  = now(),
+    last_error   = NULL
+WHERE id = $1 AND attempts = $2 AND status = 'pending'`
+
+	return j.update(ctx, query, id, attempt)
+}
+
+// RetryJob failed attempt, job is run again at runAt
+func (j *Jobs) RetryJob(ctx context.Context, id int64, attempt int, runAt time.Time, lastError string) (err error) {
+	const query = `-- name: job_retry
+UPDATE jobs
+SET updated_at   = now(),
+    run_at       = $3,
+    locked_until = NULL,
+    last_error   = $4
+WHERE id = $1 AND attempts = $2 AND status = 'pending'`
+
+	return j.update(ctx, query, id, attempt, runAt, lastError)
+}
+
+// FailJob failed attempt, job is not run anymore
+func (j *Jobs) FailJob(ctx context.Context, id int64, attempt int, lastError string) (err error) {
+	const query = `-- name: job_fail
+UPDATE jobs
+SET updated_at   = now(),
+    status       = 'failed',
+    locked_until = NULL,
+    finished_at  = now(),
+    last_error   = $3
+WHERE id = $1 AND attempts = $2 AND status = 'pending'`
+
+	return j.update(ctx, query, id, attempt, lastError)
+}
+
+// PurgeJobs delete jobs finished before
+func (j *Jobs) PurgeJobs(ctx context.Context, before time.Time) (count int64, err error) {
+	const query = `-- name: job_purge
+DELETE FROM jobs WHERE status <> 'pending' AND finished_at < $1`
+
+	res, err := j.db.DB(ctx).ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	count, err = res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return count, nil
+}
+
+// update job attempt, entities.ErrNotFound if attempt is not current
+func (j *Jobs) update(ctx context.Context, query string, args ...interface{}) error {
+	res, err := j.db.DB(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if count == 0 {
+		return entities.ErrNotFound
+	}
+
+	return nil
+}
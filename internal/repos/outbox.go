@@ -0,0 +1,168 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/andrdru/go-template/internal/entities"
+)
+
+type Outbox struct {
+	db transactor
+}
+
+func NewOutbox(db transactor) *Outbox {
+	return &Outbox{
+		db: db,
+	}
+}
+
+// EnqueueOutbox insert event, within transaction of context if open
+func (o *Outbox) EnqueueOutbox(ctx context.Context, event entities.OutboxEvent) (id int64, err error) {
+	const query = `-- name: outbox_enqueue
+INSERT INTO outbox_events(topic, name, key, payload) VALUES($1, $2, $3, $4) RETURNING id`
+
+	err = o.db.DB(ctx).QueryRowContext(ctx, query,
+		event.Topic,
+		event.Name,
+		event.Key,
+		event.Payload,
+	).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// ClaimOutbox lease due events for lockFor in enqueue order
+// events claimed by other relays are skipped until their lease expires
+func (o *Outbox) ClaimOutbox(ctx context.Context, limit int, lockFor time.Duration) (events []entities.OutboxEvent, err error) {
+	const query = `-- name: outbox_claim
+UPDATE outbox_events
+SET updated_at   = now(),
+    locked_until = now() + $2 * interval '1 millisecond'
+WHERE id IN (SELECT id
+             FROM outbox_events
+             WHERE delivered_at IS NULL
+               AND dead_at IS NULL
+               AND next_attempt_at <= now()
+               AND (locked_until IS NULL OR locked_until < now())
+             ORDER BY next_attempt_at, id
+             LIMIT $1 FOR UPDATE SKIP LOCKED)
+RETURNING id, created_at, topic, name, key, payload, attempts`
+
+	rows, err := o.db.DB(ctx).QueryContext(ctx, query, limit, lockFor.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	events = make([]entities.OutboxEvent, 0, limit)
+	for rows.Next() {
+		var event entities.OutboxEvent
+		err = rows.Scan(
+			&event.ID,
+			&event.CreatedAt,
+			&event.Topic,
+			&event.Name,
+			&event.Key,
+			&event.Payload,
+			&event.Attempts,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		events = append(events, event)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	// RETURNING order is not defined
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+
+	return events, nil
+}
+
+// ReleaseOutbox end lease of events left undelivered, e.g. on shutdown
+func (o *Outbox) ReleaseOutbox(ctx context.Context, ids []int64) (err error) {
+	const query = `-- name: outbox_release
+UPDATE outbox_events SET updated_at = now(), locked_until = NULL WHERE id = ANY ($1)`
+
+	_, err = o.db.DB(ctx).ExecContext(ctx, query, pq.Array(ids))
+
+	return err
+}
+
+// DeliveredOutbox .
+func (o *Outbox) DeliveredOutbox(ctx context.Context, id int64) (err error) {
+	const query = `-- name: outbox_delivered
+UPDATE outbox_events SET updated_at = now(), locked_until = NULL, delivered_at = now() WHERE id = $1`
+
+	_, err = o.db.DB(ctx).ExecContext(ctx, query, id)
+
+	return err
+}
+
+// RetryOutbox failed delivery is attempted again at nextAttemptAt
+func (o *Outbox) RetryOutbox(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) (err error) {
+	const query = `-- name: outbox_retry
+UPDATE outbox_events
+SET updated_at      = now(),
+    attempts        = attempts + 1,
+    locked_until    = NULL,
+    next_attempt_at = $2,
+    last_error      = $3
+WHERE id = $1`
+
+	_, err = o.db.DB(ctx).ExecContext(ctx, query, id, nextAttemptAt, lastError)
+
+	return err
+}
+
+// DeadOutbox move to dead letters, event is not delivered anymore
+func (o *Outbox) DeadOutbox(ctx context.Context, id int64, lastError string) (err error) {
+	const query = `-- name: outbox_dead
+UPDATE outbox_events
+SET updated_at   = now(),
+    attempts     = attempts + 1,
+    locked_until = NULL,
+    dead_at      = now(),
+    last_error   = $2
+WHERE id = $1`
+
+	_, err = o.db.DB(ctx).ExecContext(ctx, query, id, lastError)
+
+	return err
+}
+
+// PurgeOutbox delete events delivered or dead before
+func (o *Outbox) PurgeOutbox(ctx context.Context, before time.Time) (count int64, err error) {
+	const query = `-- name: outbox_purge
+DELETE FROM outbox_events WHERE delivered_at < $1 OR dead_at < $1`
+
+	res, err := o.db.DB(ctx).ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	count, err = res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,91 @@
+package repos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andrdru/go-template/internal/pagination"
+)
+
+var (
+	// keysetOps sql operators by filter operator
+	keysetOps = map[pagination.Op]string{
+		pagination.OpEq:  "=",
+		pagination.OpNe:  "<>",
+		pagination.OpGt:  ">",
+		pagination.OpGte: ">=",
+		pagination.OpLt:  "<",
+		pagination.OpLte: "<=",
+	}
+)
+
+// keysetSQL build WHERE, ORDER BY and LIMIT clauses for keyset pagination
+// columns maps api fields to sql columns, sort columns should be NOT NULL.
+// idColumn is a tie-breaker for equal sort values.
+// base conditions use placeholders $1..$len(baseArgs).
+// LIMIT is one more than params.Limit to detect next page
+func keysetSQL(
+	params pagination.Params,
+	columns map[string]string,
+	idColumn string,
+	base []string,
+	baseArgs ...any,
+) (clause string, args []any, err error) {
+	args = append(args, baseArgs...)
+	conds := append([]string(nil), base...)
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for _, filter := range params.Filters {
+		column, ok := columns[filter.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("filter field %s: unknown column", filter.Field)
+		}
+
+		if filter.Op == pagination.OpIn {
+			placeholders := make([]string, 0, len(filter.Values))
+			for _, v := range filter.Values {
+				placeholders = append(placeholders, arg(v))
+			}
+
+			conds = append(conds, fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")))
+			continue
+		}
+
+		op, ok := keysetOps[filter.Op]
+		if !ok {
+			return "", nil, fmt.Errorf("filter %s: unknown operator %s", filter.Field, filter.Op)
+		}
+
+		conds = append(conds, fmt.Sprintf("%s %s %s", column, op, arg(filter.Values[0])))
+	}
+
+	sortColumn, ok := columns[params.Sort]
+	if !ok {
+		return "", nil, fmt.Errorf("sort field %s: unknown column", params.Sort)
+	}
+
+	cmp, dir := ">", "ASC"
+	if params.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if params.Cursor != nil {
+		conds = append(conds, fmt.Sprintf("(%s, %s) %s (%s, %s)",
+			sortColumn, idColumn, cmp, arg(params.Cursor.Value), arg(params.Cursor.ID)))
+	}
+
+	var sb strings.Builder
+	if len(conds) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conds, " AND "))
+	}
+
+	_, _ = fmt.Fprintf(&sb, " ORDER BY %s %s, %s %s LIMIT %s", sortColumn, dir, idColumn, dir, arg(params.Limit+1))
+
+	return sb.String(), args, nil
+}
@@ -8,29 +8,31 @@ import (
 	"time"
 
 	"github.com/andrdru/go-template/internal/entities"
-	"github.com/andrdru/go-template/internal/metrics"
-	"github.com/andrdru/go-template/tx"
+	"github.com/andrdru/go-template/internal/pagination"
 )
 
 type User struct {
 	db transactor
 }
 
-func NewUser(db *sql.DB) *User {
+// usersListColumns users list fields to sql columns
+var usersListColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+	"email":      "email",
+}
+
+func NewUser(db transactor) *User {
 	return &User{
-		db: tx.NewTX(db),
+		db: db,
 	}
 }
 
 // CreateUser .
 func (u *User) CreateUser(ctx context.Context, user entities.User) (id int64, err error) {
-	start := time.Now()
-	defer func() {
-		u.handleMetric("user_create", time.Since(start), err)
-	}()
-
-	const query = `INSERT INTO users(email, passhash) VALUES($1, $2) RETURNING id`
-	err = u.db.DB(ctx).QueryRowContext(ctx, query,
+	const query = `-- name: user_create
+INSERT INTO users(email, passhash) VALUES($1, $2) RETURNING id`
+	err = u.db.WriteDB(ctx).QueryRowContext(ctx, query,
 		user.Email,
 		user.Passhash,
 	).Scan(&id)
@@ -43,19 +45,20 @@ func (u *User) CreateUser(ctx context.Context, user entities.User) (id int64, er
 }
 
 func (u *User) Session(ctx context.Context, token string) (session entities.Session, err error) {
-	start := time.Now()
-	defer func() {
-		u.handleMetric("session_get", time.Since(start), err)
-	}()
-
-	const query = `SELECT id,
-       created_at,
-       updated_at,
-       deleted_at,
-       user_id,
-       token,
-       extra
-FROM sessions WHERE token = $1 and deleted_at IS NULL`
+	const query = `-- name: session_get
+SELECT s.id,
+       s.created_at,
+       s.updated_at,
+       s.deleted_at,
+       s.user_id,
+       s.token,
+       s.extra,
+       u.is_admin
+FROM sessions s
+    JOIN users u ON u.id = s.user_id
+WHERE s.token = $1 AND s.deleted_at IS NULL AND u.deleted_at IS NULL`
+
+	session.User = &entities.User{}
 
 	err = u.db.DB(ctx).QueryRowContext(ctx, query, token).Scan(
 		&session.ID,
@@ -65,6 +68,7 @@ FROM sessions WHERE token = $1 and deleted_at IS NULL`
 		&session.UserID,
 		&session.Token,
 		&session.Extra,
+		&session.User.IsAdmin,
 	)
 
 	if err != nil {
@@ -75,18 +79,16 @@ FROM sessions WHERE token = $1 and deleted_at IS NULL`
 		return entities.Session{}, err
 	}
 
+	session.User.ID = session.UserID
+
 	return session, nil
 }
 
 func (u *User) CreateSession(ctx context.Context, session entities.Session) (err error) {
-	start := time.Now()
-	defer func() {
-		u.handleMetric("session_create", time.Since(start), err)
-	}()
-
-	const query = `INSERT INTO sessions(user_id, token, extra) VALUES($1, $2, $3)`
+	const query = `-- name: session_create
+INSERT INTO sessions(user_id, token, extra) VALUES($1, $2, $3)`
 
-	_, err = u.db.DB(ctx).ExecContext(ctx, query,
+	_, err = u.db.WriteDB(ctx).ExecContext(ctx, query,
 		session.UserID,
 		session.Token,
 		session.Extra,
@@ -96,14 +98,10 @@ func (u *User) CreateSession(ctx context.Context, session entities.Session) (err
 }
 
 func (u *User) DeleteSession(ctx context.Context, accessToken string) (err error) {
-	start := time.Now()
-	defer func() {
-		u.handleMetric("session_delete", time.Since(start), err)
-	}()
-
-	const query = `UPDATE sessions SET deleted_at = now() WHERE token = $1`
+	const query = `-- name: session_delete
+UPDATE sessions SET deleted_at = now() WHERE token = $1`
 
-	res, err := u.db.DB(ctx).ExecContext(ctx, query, accessToken)
+	res, err := u.db.WriteDB(ctx).ExecContext(ctx, query, accessToken)
 	if err != nil {
 		return fmt.Errorf("exec: %w", err)
 	}
@@ -120,18 +118,33 @@ func (u *User) DeleteSession(ctx context.Context, accessToken string) (err error
 	return nil
 }
 
-func (u *User) User(ctx context.Context, email string) (user entities.User, err error) {
-	start := time.Now()
-	defer func() {
-		u.handleMetric("user_get", time.Since(start), err)
-	}()
+// PurgeSessions delete sessions deleted before
+func (u *User) PurgeSessions(ctx context.Context, before time.Time) (count int64, err error) {
+	const query = `-- name: sessions_purge
+DELETE FROM sessions WHERE deleted_at < $1`
+
+	res, err := u.db.DB(ctx).ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
 
-	const query = `SELECT id,
+	count, err = res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return count, nil
+}
+
+func (u *User) User(ctx context.Context, email string) (user entities.User, err error) {
+	const query = `-- name: user_get
+SELECT id,
        created_at,
        updated_at,
        deleted_at,
        email,
-       passhash
+       passhash,
+       is_admin
 FROM users WHERE email=$1 AND deleted_at IS NULL`
 
 	err = u.db.DB(ctx).QueryRowContext(ctx, query, email).Scan(
@@ -141,6 +154,7 @@ FROM users WHERE email=$1 AND deleted_at IS NULL`
 		&user.DeletedAt,
 		&user.Email,
 		&user.Passhash,
+		&user.IsAdmin,
 	)
 
 	if err != nil {
@@ -154,6 +168,51 @@ FROM users WHERE email=$1 AND deleted_at IS NULL`
 	return user, nil
 }
 
-func (_ *User) handleMetric(name string, d time.Duration, err error) {
-	metrics.HistogramObserverDB("postgres", name, entities.Err(err)).Observe(d.Seconds())
+// Users list page, one extra item is fetched to detect next page
+func (u *User) Users(ctx context.Context, params pagination.Params) (users []entities.User, err error) {
+	clause, args, err := keysetSQL(params, usersListColumns, "id", []string{"deleted_at IS NULL"})
+	if err != nil {
+		return nil, fmt.Errorf("keyset: %w", err)
+	}
+
+	query := `-- name: users_list
+SELECT id,
+       created_at,
+       updated_at,
+       email,
+       is_admin
+FROM users` + clause
+
+	// admin list tolerates replica lag
+	rows, err := u.db.ReadDB(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	users = make([]entities.User, 0, params.Limit+1)
+	for rows.Next() {
+		var user entities.User
+		err = rows.Scan(
+			&user.ID,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.Email,
+			&user.IsAdmin,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	return users, nil
 }
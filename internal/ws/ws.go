@@ -0,0 +1,377 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/andrdru/go-template/internal/metrics"
+)
+
+type (
+	// Hub websocket connections registry, connections are server push only:
+	// client messages are read for keepalive and discarded
+	Hub struct {
+		logger   *slog.Logger
+		upgrader websocket.Upgrader
+
+		mu     sync.Mutex
+		conns  map[*Conn]struct{}
+		closed bool
+		// wg connections read and write goroutines
+		wg sync.WaitGroup
+
+		pingPeriod     time.Duration
+		pongTimeout    time.Duration
+		writeTimeout   time.Duration
+		sendBuffer     int
+		maxMessageSize int64
+	}
+
+	// Conn client connection, messages are written by own goroutine
+	Conn struct {
+		hub    *Hub
+		conn   *websocket.Conn
+		userID int64
+		send   chan []byte
+		// done closed when connection is dropped by hub
+		done      chan struct{}
+		closeCode int
+	}
+
+	options struct {
+		pingPeriod     time.Duration
+		pongTimeout    time.Duration
+		writeTimeout   time.Duration
+		sendBuffer     int
+		maxMessageSize int64
+		checkOrigin    func(r *http.Request) bool
+	}
+
+	Option func(*options)
+)
+
+var (
+	// PingPeriodDefault keepalive ping interval
+	PingPeriodDefault = 30 * time.Second
+	// PongTimeoutDefault connection without pong or message is dropped, should exceed ping period
+	PongTimeoutDefault = 60 * time.Second
+	// WriteTimeoutDefault single message write deadline
+	WriteTimeoutDefault = 10 * time.Second
+	// SendBufferDefault messages queued per connection before slow client is dropped
+	SendBufferDefault = 64
+	// MaxMessageSizeDefault client message size limit, bytes
+	MaxMessageSizeDefault int64 = 64 << 10
+
+	ErrClosed = errors.New("hub closed")
+)
+
+// NewHub .
+func NewHub(logger *slog.Logger, opts ...Option) *Hub {
+	args := &options{
+		pingPeriod:     PingPeriodDefault,
+		pongTimeout:    PongTimeoutDefault,
+		writeTimeout:   WriteTimeoutDefault,
+		sendBuffer:     SendBufferDefault,
+		maxMessageSize: MaxMessageSizeDefault,
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	return &Hub{
+		logger: logger,
+		upgrader: websocket.Upgrader{
+			// nil checks Origin is same host
+			CheckOrigin: args.checkOrigin,
+		},
+		conns:          make(map[*Conn]struct{}),
+		pingPeriod:     args.pingPeriod,
+		pongTimeout:    args.pongTimeout,
+		writeTimeout:   args.writeTimeout,
+		sendBuffer:     args.sendBuffer,
+		maxMessageSize: args.maxMessageSize,
+	}
+}
+
+// Serve upgrade request to websocket connection of user and start its read and write goroutines.
+// Upgrade errors are already responded to client
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, userID int64) (*Conn, error) {
+	h.mu.Lock()
+	closed := h.closed
+	h.mu.Unlock()
+
+	if closed {
+		return nil, ErrClosed
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("upgrade: %w", err)
+	}
+
+	c := &Conn{
+		hub:    h,
+		conn:   conn,
+		userID: userID,
+		send:   make(chan []byte, h.sendBuffer),
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(h.writeTimeout))
+		_ = conn.Close()
+		return nil, ErrClosed
+	}
+
+	h.conns[c] = struct{}{}
+	metrics.GaugeWebSocketConnections().Inc()
+
+	h.wg.Add(2)
+	go c.readLoop()
+	go c.writeLoop()
+
+	return c, nil
+}
+
+// Close drop all connections with going away status, wait until they are closed
+func (h *Hub) Close(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	for c := range h.conns {
+		h.drop(c, websocket.CloseGoingAway)
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait connections: %w", ctx.Err())
+	}
+}
+
+// UserID connection owner
+func (c *Conn) UserID() int64 {
+	return c.userID
+}
+
+// Send queue message, false if connection is closed or too slow and dropped
+func (c *Conn) Send(data []byte) bool {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	return c.hub.enqueue(c, data)
+}
+
+// Done closed when connection is dropped
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close connection with normal closure status
+func (c *Conn) Close() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	c.hub.drop(c, websocket.CloseNormalClosure)
+}
+
+func (c *Conn) readLoop() {
+	defer c.hub.wg.Done()
+	defer c.Close()
+
+	c.conn.SetReadLimit(c.hub.maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.hub.pongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.pongTimeout))
+	})
+
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.hub.logger.Info("websocket read",
+					slog.Int64("user_id", c.userID),
+					slog.Any("error", err),
+				)
+			}
+			return
+		}
+
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.hub.pongTimeout))
+		metrics.CounterWebSocketMessages("in").Inc()
+	}
+}
+
+func (c *Conn) writeLoop() {
+	defer c.hub.wg.Done()
+	// unblocks readLoop
+	defer c.conn.Close()
+
+	ticker := time.NewTicker(c.hub.pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data, time.Now().Add(c.hub.writeTimeout)); err != nil {
+				c.Close()
+				return
+			}
+
+		case <-ticker.C:
+			if err := c.write(websocket.PingMessage, nil, time.Now().Add(c.hub.writeTimeout)); err != nil {
+				c.Close()
+				return
+			}
+
+		case <-c.done:
+			c.drain()
+			return
+		}
+	}
+}
+
+// drain write queued messages and close frame within single write timeout
+func (c *Conn) drain() {
+	deadline := time.Now().Add(c.hub.writeTimeout)
+
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data, deadline); err != nil {
+				return
+			}
+		default:
+			_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, ""), deadline)
+			return
+		}
+	}
+}
+
+func (c *Conn) write(messageType int, data []byte, deadline time.Time) error {
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+
+	if err := c.conn.WriteMessage(messageType, data); err != nil {
+		return err
+	}
+
+	if messageType == websocket.TextMessage {
+		metrics.CounterWebSocketMessages("out").Inc()
+	}
+
+	return nil
+}
+
+// enqueue lock required
+func (h *Hub) enqueue(c *Conn, data []byte) bool {
+	if _, ok := h.conns[c]; !ok {
+		return false
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		metrics.CounterWebSocketMessages("dropped").Inc()
+		h.drop(c, websocket.CloseTryAgainLater)
+		return false
+	}
+}
+
+// drop unregister connection and signal write goroutine to close it, lock required
+func (h *Hub) drop(c *Conn, closeCode int) {
+	if _, ok := h.conns[c]; !ok {
+		return
+	}
+
+	delete(h.conns, c)
+
+	c.closeCode = closeCode
+	close(c.done)
+
+	metrics.GaugeWebSocketConnections().Dec()
+}
+
+// AllowedOrigins Origin check accepting same host and listed origins.
+// "*" is ignored: upgrade is authenticated by session cookie, any site could hijack it
+func AllowedOrigins(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || (origin != "*" && slices.Contains(origins, origin)) {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+
+		return strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// WithPingPeriod keepalive ping interval
+func WithPingPeriod(period time.Duration) Option {
+	return func(args *options) {
+		args.pingPeriod = period
+	}
+}
+
+// WithPongTimeout connection without pong or message is dropped
+func WithPongTimeout(timeout time.Duration) Option {
+	return func(args *options) {
+		args.pongTimeout = timeout
+	}
+}
+
+// WithWriteTimeout single message write deadline
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(args *options) {
+		args.writeTimeout = timeout
+	}
+}
+
+// WithSendBuffer messages queued per connection
+func WithSendBuffer(size int) Option {
+	return func(args *options) {
+		args.sendBuffer = size
+	}
+}
+
+// WithMaxMessageSize client message size limit, bytes
+func WithMaxMessageSize(size int64) Option {
+	return func(args *options) {
+		args.maxMessageSize = size
+	}
+}
+
+// WithCheckOrigin upgrade Origin check, same host only by default
+func WithCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(args *options) {
+		args.checkOrigin = check
+	}
+}
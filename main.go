@@ -25,6 +25,7 @@ const (
 	serviceName = "service"
 
 	scriptExample = "example"
+	scriptWorker  = "worker"
 )
 
 func main() {
@@ -51,6 +52,9 @@ func main() {
 
 	case scriptExample:
 		code = script_example.Run(logger)
+
+	case scriptWorker:
+		code = app.RunWorker(logger, *f.configPath)
 	}
 
 	os.Exit(code)
@@ -59,7 +63,7 @@ func main() {
 func initFlags() (fv flags) {
 	fv.isHelp = flag.Bool("help", false, "Print help and exit")
 	fv.configPath = flag.String("config", "config.yaml", "path to config.yml")
-	fv.script = flag.String("script", "", "Run in script mode. One of: test-script")
+	fv.script = flag.String("script", "", "Run in script mode. One of: example, worker")
 
 	flag.Parse()
 	return fv
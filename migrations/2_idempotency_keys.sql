@@ -0,0 +1,32 @@
+-- +migrate Up
+CREATE TABLE idempotency_keys
+(
+    id           BIGSERIAL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+    key          TEXT                     NOT NULL,
+    fingerprint  TEXT                     NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE NULL,
+    lock_token   TEXT                     NULL,
+    status       INT                      NULL,
+    headers      JSONB                    NULL,
+    body         BYTEA                    NULL,
+
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idempotency_keys_key_idx ON idempotency_keys (key);
+
+comment
+    ON COLUMN idempotency_keys.key IS 'Idempotency-Key header scoped by route and user';
+comment
+    ON COLUMN idempotency_keys.fingerprint IS 'request hash to detect key reuse with other payload';
+comment
+    ON COLUMN idempotency_keys.locked_until IS 'request in progress lock';
+comment
+    ON COLUMN idempotency_keys.lock_token IS 'lock owner, taken over lock is not saved or released by previous owner';
+comment
+    ON COLUMN idempotency_keys.status IS 'saved response status, NULL while in progress';
+
+-- +migrate Down
+DROP TABLE IF EXISTS idempotency_keys;
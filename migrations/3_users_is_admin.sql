@@ -0,0 +1,19 @@
+-- +migrate Up
+ALTER TABLE users
+    ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+comment
+    ON COLUMN users.is_admin IS 'admin access';
+
+-- keyset pagination of users list
+CREATE INDEX users_created_at_id_idx ON users (created_at, id);
+
+-- test user is admin
+UPDATE users
+SET is_admin = true
+WHERE email = 'test@test.test';
+
+-- +migrate Down
+DROP INDEX IF EXISTS users_created_at_id_idx;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,39 @@
+-- +migrate Up
+CREATE TABLE outbox_events
+(
+    id              BIGSERIAL,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+    topic           TEXT                     NOT NULL,
+    name            TEXT                     NOT NULL,
+    key             TEXT                     NOT NULL DEFAULT '',
+    payload         JSONB                    NOT NULL,
+    attempts        INT                      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    locked_until    TIMESTAMP WITH TIME ZONE NULL,
+    delivered_at    TIMESTAMP WITH TIME ZONE NULL,
+    dead_at         TIMESTAMP WITH TIME ZONE NULL,
+    last_error      TEXT                     NULL,
+
+    PRIMARY KEY (id)
+);
+-- relay polling
+CREATE INDEX outbox_events_pending_idx ON outbox_events (next_attempt_at, id)
+    WHERE delivered_at IS NULL AND dead_at IS NULL;
+
+comment
+    ON COLUMN outbox_events.topic IS 'destination: webhook route, redis stream etc';
+comment
+    ON COLUMN outbox_events.name IS 'event type, e.g. user.registered';
+comment
+    ON COLUMN outbox_events.key IS 'entity key for consumers, e.g. user id';
+comment
+    ON COLUMN outbox_events.next_attempt_at IS 'delivery is retried with backoff';
+comment
+    ON COLUMN outbox_events.locked_until IS 'lease: event in delivery is claimed by other relay after';
+comment
+    ON COLUMN outbox_events.dead_at IS 'dead letter: delivery attempts exhausted';
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,40 @@
+-- +migrate Up
+CREATE TABLE jobs
+(
+    id           BIGSERIAL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+
+    name         TEXT                     NOT NULL,
+    payload      JSONB                    NOT NULL,
+    unique_key   TEXT                     NULL,
+    status       TEXT                     NOT NULL DEFAULT 'pending',
+    attempts     INT                      NOT NULL DEFAULT 0,
+    max_attempts INT                      NOT NULL,
+    run_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    locked_until TIMESTAMP WITH TIME ZONE NULL,
+    finished_at  TIMESTAMP WITH TIME ZONE NULL,
+    last_error   TEXT                     NULL,
+
+    PRIMARY KEY (id)
+);
+-- workers polling
+CREATE INDEX jobs_pending_idx ON jobs (run_at, id)
+    WHERE status = 'pending';
+-- one unfinished job by key
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (name, unique_key)
+    WHERE unique_key IS NOT NULL AND status = 'pending';
+
+comment
+    ON COLUMN jobs.name IS 'handler name';
+comment
+    ON COLUMN jobs.unique_key IS 'job is not enqueued while other pending job has same name and key';
+comment
+    ON COLUMN jobs.status IS 'one of: pending, done, failed';
+comment
+    ON COLUMN jobs.run_at IS 'job is not run before, delayed and retried jobs';
+comment
+    ON COLUMN jobs.locked_until IS 'visibility timeout: job in progress is taken by other worker after';
+
+-- +migrate Down
+DROP TABLE IF EXISTS jobs;
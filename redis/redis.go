@@ -1,7 +1,9 @@
 package redis
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	redigoRedis "github.com/gomodule/redigo/redis"
@@ -60,6 +62,19 @@ func NewRedis(pool *redigoRedis.Pool, opts ...Option) *Redis {
 	}
 }
 
+// Ping check connection, ctx limits waiting for pool and reply
+func (r *Redis) Ping(ctx context.Context) error {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("get conn: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, err = redigoRedis.DoContext(conn, ctx, "PING")
+
+	return err
+}
+
 // Get by key
 func (r *Redis) Get(key string) (data any, err error) {
 	data, err = redigoRedis.DoWithTimeout(
@@ -171,3 +186,43 @@ func (r *Redis) Incr(key string) (val int64, err error) {
 
 	return val, nil
 }
+
+// NewScript init lua script with keyCount keys
+func NewScript(keyCount int, src string) *redigoRedis.Script {
+	return redigoRedis.NewScript(keyCount, src)
+}
+
+// Eval run lua script with EVALSHA, fallback to EVAL if script is not loaded
+func (r *Redis) Eval(script *redigoRedis.Script, keysAndArgs ...any) (data any, err error) {
+	conn := r.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.operationTimeout)
+	defer cancel()
+
+	return script.DoContext(ctx, conn, keysAndArgs...)
+}
+
+// XAdd append entry to stream, returns entry id
+// maxLen approximately trims stream, zero keeps all entries
+func (r *Redis) XAdd(ctx context.Context, stream string, maxLen int64, fieldsAndValues ...any) (id string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.operationTimeout)
+	defer cancel()
+
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get conn: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	args := []any{stream}
+	if maxLen > 0 {
+		args = append(args, "MAXLEN", "~", maxLen)
+	}
+	args = append(args, "*")
+	args = append(args, fieldsAndValues...)
+
+	return redigoRedis.String(redigoRedis.DoContext(conn, ctx, "XADD", args...))
+}
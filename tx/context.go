@@ -7,6 +7,15 @@ import (
 
 type (
 	ctxKey string
+
+	// transaction state shared by nested TX calls
+	transaction struct {
+		tx *sql.Tx
+		// savepoints counter for unique names
+		savepoints int
+		hooks      hooks
+		label      string
+	}
 )
 
 const (
@@ -14,18 +23,23 @@ const (
 )
 
 // ctxSetTx tx to context
-func ctxSetTx(parent context.Context, item *sql.Tx) context.Context {
+func ctxSetTx(parent context.Context, item *transaction) context.Context {
 	return context.WithValue(parent, keyTransaction, item)
 }
 
 // ctxGetTx tx from context
-func ctxGetTx(ctx context.Context) *sql.Tx {
+func ctxGetTx(ctx context.Context) *transaction {
 	data := ctx.Value(keyTransaction)
 	if data != nil {
-		if ret, ok := data.(*sql.Tx); ok {
+		if ret, ok := data.(*transaction); ok {
 			return ret
 		}
 	}
 
 	return nil
 }
+
+// ctxWithoutTx context of hooks, DB() is not bound to finished transaction
+func ctxWithoutTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyTransaction, nil)
+}
@@ -0,0 +1,68 @@
+package tx
+
+import (
+	"context"
+)
+
+type (
+	// Hook called after transaction end with context out of transaction
+	Hook func(ctx context.Context)
+
+	hooks struct {
+		onCommit   []Hook
+		onRollback []Hook
+	}
+)
+
+// OnCommit call hook after transaction commit, e.g. to publish events.
+// Called immediately out of transaction.
+// Hook of rolled back savepoint is discarded
+func OnCommit(ctx context.Context, hook Hook) {
+	t := ctxGetTx(ctx)
+	if t == nil {
+		hook(ctx)
+		return
+	}
+
+	t.hooks.onCommit = append(t.hooks.onCommit, hook)
+}
+
+// OnRollback call hook after transaction or savepoint rollback.
+// Never called out of transaction
+func OnRollback(ctx context.Context, hook Hook) {
+	t := ctxGetTx(ctx)
+	if t == nil {
+		return
+	}
+
+	t.hooks.onRollback = append(t.hooks.onRollback, hook)
+}
+
+// mark hooks registered so far
+func (h *hooks) mark() (commit int, rollback int) {
+	return len(h.onCommit), len(h.onRollback)
+}
+
+// rollbackTo run rollback hooks registered after mark, discard commit hooks after mark
+func (h *hooks) rollbackTo(ctx context.Context, commit int, rollback int) {
+	run := h.onRollback[rollback:]
+
+	h.onCommit = h.onCommit[:commit]
+	h.onRollback = h.onRollback[:rollback]
+
+	for _, hook := range run {
+		hook(ctx)
+	}
+}
+
+func (h *hooks) committed(ctx context.Context) {
+	for _, hook := range h.onCommit {
+		hook(ctx)
+	}
+}
+
+func (h *hooks) rolledBack(ctx context.Context) {
+	for _, hook := range h.onRollback {
+		hook(ctx)
+	}
+}
@@ -0,0 +1,240 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Replicas read replicas with health tracking
+	// unhealthy replica is skipped, primary is used if none is healthy
+	Replicas struct {
+		replicas []*replica
+		next     atomic.Uint64
+	}
+
+	replica struct {
+		db      *sql.DB
+		healthy atomic.Bool
+	}
+
+	// replicaExecutor run query on primary if replica connection fails,
+	// replica is skipped until next Watch check
+	replicaExecutor struct {
+		replica *replica
+		primary *sql.DB
+	}
+
+	// sticky last write time by key, reads of key go to primary for a while
+	sticky struct {
+		mu       sync.Mutex
+		duration time.Duration
+		writes   map[string]time.Time
+		sweepAt  time.Time
+	}
+)
+
+const (
+	keyReadOnly  ctxKey = "read_only"
+	keyStickyKey ctxKey = "sticky_key"
+)
+
+var (
+	// ReplicaCheckTimeout replica ping timeout
+	ReplicaCheckTimeout = time.Second
+)
+
+// NewReplicas all replicas are healthy until checked
+func NewReplicas(dbs ...*sql.DB) *Replicas {
+	r := &Replicas{}
+
+	for _, db := range dbs {
+		item := &replica{db: db}
+		item.healthy.Store(true)
+		r.replicas = append(r.replicas, item)
+	}
+
+	return r
+}
+
+// Watch ping replicas every interval until ctx is done
+func (r *Replicas) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx)
+		}
+	}
+}
+
+// Close replicas connections
+func (r *Replicas) Close() error {
+	var err error
+	for _, item := range r.replicas {
+		if errClose := item.db.Close(); errClose != nil {
+			err = errClose
+		}
+	}
+
+	return err
+}
+
+func (r *Replicas) check(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, item := range r.replicas {
+		wg.Add(1)
+		go func(item *replica) {
+			defer wg.Done()
+
+			ctxPing, cancel := context.WithTimeout(ctx, ReplicaCheckTimeout)
+			defer cancel()
+
+			item.healthy.Store(item.db.PingContext(ctxPing) == nil)
+		}(item)
+	}
+
+	wg.Wait()
+}
+
+// pick healthy replica round robin, nil if none
+func (r *Replicas) pick() *replica {
+	if r == nil || len(r.replicas) == 0 {
+		return nil
+	}
+
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		item := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if item.healthy.Load() {
+			return item
+		}
+	}
+
+	return nil
+}
+
+func (e *replicaExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := e.replica.db.ExecContext(ctx, query, args...)
+	if e.failover(ctx, err) {
+		return e.primary.ExecContext(ctx, query, args...)
+	}
+
+	return res, err
+}
+
+func (e *replicaExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := e.replica.db.QueryRowContext(ctx, query, args...)
+	if e.failover(ctx, row.Err()) {
+		return e.primary.QueryRowContext(ctx, query, args...)
+	}
+
+	return row
+}
+
+func (e *replicaExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := e.replica.db.QueryContext(ctx, query, args...)
+	if e.failover(ctx, err) {
+		return e.primary.QueryContext(ctx, query, args...)
+	}
+
+	return rows, err
+}
+
+// failover query error is replica connection error, marks replica unhealthy
+func (e *replicaExecutor) failover(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || !isConnError(err) {
+		return false
+	}
+
+	e.replica.healthy.Store(false)
+
+	return true
+}
+
+// isConnError connection is refused or broken
+func isConnError(err error) bool {
+	var netErr net.Error
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+func newSticky(duration time.Duration) *sticky {
+	return &sticky{
+		duration: duration,
+		writes:   make(map[string]time.Time),
+		sweepAt:  time.Now().Add(duration),
+	}
+}
+
+func (s *sticky) write(key string) {
+	if s.duration <= 0 || key == "" {
+		return
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes[key] = now
+
+	if now.After(s.sweepAt) {
+		for k, at := range s.writes {
+			if now.Sub(at) > s.duration {
+				delete(s.writes, k)
+			}
+		}
+
+		s.sweepAt = now.Add(s.duration)
+	}
+}
+
+func (s *sticky) active(key string) bool {
+	if s.duration <= 0 || key == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	at, ok := s.writes[key]
+
+	return ok && time.Since(at) < s.duration
+}
+
+// MarkReadOnly DB() of context returns replica out of transaction
+func MarkReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyReadOnly, true)
+}
+
+// WithStickyKey client of context, e.g. user id.
+// Reads of client go to primary for a while after its write, so client reads own writes
+func WithStickyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyStickyKey, key)
+}
+
+func ctxReadOnly(ctx context.Context) bool {
+	ok, _ := ctx.Value(keyReadOnly).(bool)
+	return ok
+}
+
+func ctxStickyKey(ctx context.Context) string {
+	key, _ := ctx.Value(keyStickyKey).(string)
+	return key
+}
@@ -0,0 +1,80 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+type (
+	// sqlState implemented by pq.Error and pgconn.PgError
+	sqlState interface {
+		SQLState() string
+	}
+
+	nopObserver struct{}
+)
+
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+var (
+	// RetryBackoffBaseDefault delay before second attempt
+	RetryBackoffBaseDefault = 10 * time.Millisecond
+	// RetryBackoffMaxDefault delay limit
+	RetryBackoffMaxDefault = time.Second
+)
+
+// IsRetryable serialization failure or deadlock, transaction may succeed if run again
+func IsRetryable(err error) bool {
+	var state sqlState
+	if !errors.As(err, &state) {
+		return false
+	}
+
+	switch state.SQLState() {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff random delay up to exponentially growing limit
+func backoff(attempt int, base time.Duration, maxDelay time.Duration) time.Duration {
+	limit := base
+	for i := 1; i < attempt && limit < maxDelay; i++ {
+		limit *= 2
+	}
+
+	if limit > maxDelay {
+		limit = maxDelay
+	}
+
+	if limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(limit)) + 1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (nopObserver) TxRetry(string, int, error) {}
+
+func (nopObserver) TxGiveUp(string, int, error) {}
+
+func (nopObserver) TxDone(string, time.Duration, error) {}
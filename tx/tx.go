@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 )
 
 type (
@@ -19,14 +20,45 @@ type (
 	// detect query executor into repo with DB()
 	// process transaction with TX()
 	TX struct {
-		db *sql.DB
+		db       *sql.DB
+		replicas *Replicas
+		sticky   *sticky
+		observer Observer
+		wrap     func(QueryExecutor) QueryExecutor
+	}
+
+	// Observer transaction events, e.g. for metrics and logs
+	// label is set by WithLabel, empty by default
+	Observer interface {
+		// TxRetry transaction failed with retryable error, attempt is retried
+		TxRetry(label string, attempt int, err error)
+		// TxGiveUp retryable error on last attempt
+		TxGiveUp(label string, attempts int, err error)
+		// TxDone transaction attempt finished, err is nil if committed
+		TxDone(label string, duration time.Duration, err error)
 	}
 
 	options struct {
-		level sql.IsolationLevel
+		level       sql.IsolationLevel
+		strict      bool
+		maxAttempts int
+		backoffBase time.Duration
+		backoffMax  time.Duration
+		readOnly    bool
+		timeout     time.Duration
+		label       string
 	}
 
 	Option func(*options)
+
+	txOptions struct {
+		observer   Observer
+		replicas   *Replicas
+		stickiness time.Duration
+		wrap       func(QueryExecutor) QueryExecutor
+	}
+
+	TXOption func(*txOptions)
 )
 
 var (
@@ -35,43 +67,167 @@ var (
 )
 
 // NewTX .
-func NewTX(db *sql.DB) *TX {
+func NewTX(db *sql.DB, opts ...TXOption) *TX {
+	args := &txOptions{
+		observer: nopObserver{},
+		wrap: func(db QueryExecutor) QueryExecutor {
+			return db
+		},
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
 	return &TX{
-		db: db,
+		db:       db,
+		replicas: args.replicas,
+		sticky:   newSticky(args.stickiness),
+		observer: args.observer,
+		wrap:     args.wrap,
 	}
 }
 
 // DB ctxGetTx query executor by context
+// replica if context is marked read only, primary otherwise.
+// Write out of transaction should use WriteDB, so client reads own write
 func (T *TX) DB(ctx context.Context) QueryExecutor {
-	db := ctxGetTx(ctx)
-	if db != nil {
-		return db
+	if ctxReadOnly(ctx) {
+		return T.ReadDB(ctx)
+	}
+
+	t := ctxGetTx(ctx)
+	if t != nil {
+		return T.wrap(t.tx)
 	}
 
-	return T.db
+	return T.wrap(T.db)
 }
 
-// TX abstract logic from transaction details
-// important: processor have to use DB() calls for properly transaction handling
-func (T *TX) TX(ctx context.Context, processor func(txCtx context.Context) error, opts ...Option) error {
-	if ctxGetTx(ctx) != nil {
-		return ErrTxOpenAlready
+// WriteDB query executor for write: transaction if open, primary otherwise.
+// Out of transaction reads of client go to primary for a while, see WithStickyKey
+func (T *TX) WriteDB(ctx context.Context) QueryExecutor {
+	t := ctxGetTx(ctx)
+	if t != nil {
+		return T.wrap(t.tx)
 	}
 
+	T.sticky.write(ctxStickyKey(ctx))
+
+	return T.wrap(T.db)
+}
+
+// ReadDB query executor for read: transaction if open,
+// primary if client wrote recently or no replica is healthy, replica otherwise.
+// Query failed by replica connection error is run on primary
+func (T *TX) ReadDB(ctx context.Context) QueryExecutor {
+	t := ctxGetTx(ctx)
+	if t != nil {
+		return T.wrap(t.tx)
+	}
+
+	if r := T.replica(ctx); r != nil {
+		return T.wrap(&replicaExecutor{replica: r, primary: T.db})
+	}
+
+	return T.wrap(T.db)
+}
+
+// replica healthy replica, nil if client wrote recently or none is healthy
+func (T *TX) replica(ctx context.Context) *replica {
+	if T.sticky.active(ctxStickyKey(ctx)) {
+		return nil
+	}
+
+	return T.replicas.pick()
+}
+
+// TX abstract logic from transaction details
+// important: processor have to use DB() calls for properly transaction handling.
+// Called inside transaction, runs processor in savepoint: rolled back to on error,
+// released on success, outer transaction decides on commit. Options of nested call except WithStrict are ignored
+func (T *TX) TX(ctx context.Context, processor func(txCtx context.Context) error, opts ...Option) error {
 	var args = &options{
-		level: sql.LevelDefault,
+		level:       sql.LevelDefault,
+		maxAttempts: 1,
+		backoffBase: RetryBackoffBaseDefault,
+		backoffMax:  RetryBackoffMaxDefault,
 	}
 
 	for _, opt := range opts {
 		opt(args)
 	}
 
-	tx, err := T.db.BeginTx(ctx, &sql.TxOptions{Isolation: args.level})
+	if parent := ctxGetTx(ctx); parent != nil {
+		if args.strict {
+			return ErrTxOpenAlready
+		}
+
+		return savepoint(ctx, parent, processor)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := T.run(ctx, processor, args)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+
+		if attempt >= args.maxAttempts {
+			if args.maxAttempts > 1 {
+				T.observer.TxGiveUp(args.label, attempt, err)
+			}
+
+			return err
+		}
+
+		T.observer.TxRetry(args.label, attempt, err)
+
+		if err = sleep(ctx, backoff(attempt, args.backoffBase, args.backoffMax)); err != nil {
+			return fmt.Errorf("retry backoff: %w", err)
+		}
+	}
+}
+
+// run processor in new transaction
+func (T *TX) run(ctx context.Context, processor func(txCtx context.Context) error, args *options) (err error) {
+	start := time.Now()
+	defer func() {
+		T.observer.TxDone(args.label, time.Since(start), err)
+	}()
+
+	ctxTx := ctx
+	if args.timeout > 0 {
+		var cancel context.CancelFunc
+		ctxTx, cancel = context.WithTimeout(ctx, args.timeout)
+		defer cancel()
+	}
+
+	txOpts := &sql.TxOptions{Isolation: args.level, ReadOnly: args.readOnly}
+
+	var tx *sql.Tx
+	if r := T.readOnlyReplica(ctx, args); r != nil {
+		tx, err = r.db.BeginTx(ctxTx, txOpts)
+		if err != nil && ctxTx.Err() == nil && isConnError(err) {
+			r.healthy.Store(false)
+			tx, err = T.db.BeginTx(ctxTx, txOpts)
+		}
+	} else {
+		tx, err = T.db.BeginTx(ctxTx, txOpts)
+	}
+
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 
-	txCtx := ctxSetTx(ctx, tx)
+	if args.timeout > 0 {
+		if err = setTimeouts(ctxTx, tx, args.timeout); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("set timeouts: %w", err)
+		}
+	}
+
+	t := &transaction{tx: tx, label: args.label}
+	txCtx := ctxSetTx(ctxTx, t)
 
 	err = processor(txCtx)
 	if err != nil {
@@ -80,6 +236,8 @@ func (T *TX) TX(ctx context.Context, processor func(txCtx context.Context) error
 			err = fmt.Errorf("rollback failed %s: %w", errRollback.Error(), err)
 		}
 
+		t.hooks.rolledBack(ctx)
+
 		return fmt.Errorf("process tx: %w", err)
 	}
 
@@ -90,9 +248,76 @@ func (T *TX) TX(ctx context.Context, processor func(txCtx context.Context) error
 			err = fmt.Errorf("rollback failed %s: %w", errRollback.Error(), err)
 		}
 
+		t.hooks.rolledBack(ctx)
+
 		return fmt.Errorf("commit tx: %w", err)
 	}
 
+	if !args.readOnly {
+		T.sticky.write(ctxStickyKey(ctx))
+	}
+
+	t.hooks.committed(ctx)
+
+	return nil
+}
+
+// readOnlyReplica replica of read only transaction, nil for primary
+func (T *TX) readOnlyReplica(ctx context.Context, args *options) *replica {
+	if !args.readOnly {
+		return nil
+	}
+
+	return T.replica(ctx)
+}
+
+// setTimeouts limit statements and idle time to transaction timeout
+func setTimeouts(ctx context.Context, tx *sql.Tx, timeout time.Duration) error {
+	ms := timeout.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", ms))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL idle_in_transaction_session_timeout = %d", ms))
+
+	return err
+}
+
+// savepoint run processor in nested transaction
+func savepoint(ctx context.Context, t *transaction, processor func(txCtx context.Context) error) error {
+	t.savepoints++
+	name := fmt.Sprintf("tx_savepoint_%d", t.savepoints)
+
+	_, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return fmt.Errorf("savepoint: %w", err)
+	}
+
+	commitMark, rollbackMark := t.hooks.mark()
+
+	err = processor(ctx)
+	if err != nil {
+		_, errRollback := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		if errRollback != nil {
+			// hooks are left to outer transaction rollback
+			err = fmt.Errorf("rollback to savepoint failed %s: %w", errRollback.Error(), err)
+		} else {
+			t.hooks.rollbackTo(ctxWithoutTx(ctx), commitMark, rollbackMark)
+		}
+
+		return fmt.Errorf("process savepoint: %w", err)
+	}
+
+	_, err = t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	if err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+
 	return nil
 }
 
@@ -101,3 +326,91 @@ func WithIsolation(level sql.IsolationLevel) Option {
 		args.level = level
 	}
 }
+
+// WithStrict nested call fails with ErrTxOpenAlready instead of savepoint
+func WithStrict() Option {
+	return func(args *options) {
+		args.strict = true
+	}
+}
+
+// WithRetry run processor again in new transaction on serialization failure or deadlock,
+// up to maxAttempts in total. Processor side effects out of transaction should be idempotent
+func WithRetry(maxAttempts int) Option {
+	return func(args *options) {
+		args.maxAttempts = maxAttempts
+	}
+}
+
+// WithRetryBackoff exponential backoff between attempts with full jitter
+func WithRetryBackoff(base time.Duration, maxDelay time.Duration) Option {
+	return func(args *options) {
+		args.backoffBase = base
+		args.backoffMax = maxDelay
+	}
+}
+
+// WithObserver receive transaction events
+func WithObserver(observer Observer) TXOption {
+	return func(args *txOptions) {
+		args.observer = observer
+	}
+}
+
+// WithReplicas route reads to replicas, see ReadDB
+func WithReplicas(replicas *Replicas) TXOption {
+	return func(args *txOptions) {
+		args.replicas = replicas
+	}
+}
+
+// WithStickiness reads of client go to primary for duration after its write, see WithStickyKey
+func WithStickiness(duration time.Duration) TXOption {
+	return func(args *txOptions) {
+		args.stickiness = duration
+	}
+}
+
+// WithWrapper decorate query executors returned by DB and ReadDB, e.g. for metrics and tracing
+func WithWrapper(wrap func(db QueryExecutor) QueryExecutor) TXOption {
+	return func(args *txOptions) {
+		args.wrap = wrap
+	}
+}
+
+// ReadOnly read only transaction, runs on replica if available, see ReadDB
+func ReadOnly() Option {
+	return func(args *options) {
+		args.readOnly = true
+	}
+}
+
+// WithTimeout transaction deadline, also applied to Postgres
+// statement_timeout and idle_in_transaction_session_timeout, so abandoned transaction does not pin connection
+func WithTimeout(timeout time.Duration) Option {
+	return func(args *options) {
+		args.timeout = timeout
+	}
+}
+
+// WithLabel name transaction for metrics and logs, see Observer and Label
+func WithLabel(label string) Option {
+	return func(args *options) {
+		args.label = label
+	}
+}
+
+// InTX transaction is open in context
+func InTX(ctx context.Context) bool {
+	return ctxGetTx(ctx) != nil
+}
+
+// Label of transaction open in context, empty out of transaction
+func Label(ctx context.Context) string {
+	t := ctxGetTx(ctx)
+	if t == nil {
+		return ""
+	}
+
+	return t.label
+}
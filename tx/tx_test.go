@@ -0,0 +1,731 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	// stubDB records statements of all connections
+	stubDB struct {
+		mu  sync.Mutex
+		log []string
+
+		// connErr fails new connections
+		connErr  error
+		connects int
+	}
+
+	stubConn struct {
+		db *stubDB
+	}
+
+	stubTx struct {
+		db *stubDB
+	}
+
+	stubResult struct{}
+
+	// stubSQLError driver error with SQLSTATE like pq.Error
+	stubSQLError struct {
+		state string
+	}
+
+	// stubObserver records transaction events
+	stubObserver struct {
+		mu      sync.Mutex
+		retries []int
+		giveUps []int
+		labels  []string
+	}
+)
+
+var (
+	errProcess = errors.New("process failed")
+
+	errSerialization = stubSQLError{state: sqlStateSerializationFailure}
+)
+
+func newStub(t *testing.T) (*TX, *stubDB) {
+	t.Helper()
+
+	stub := &stubDB{}
+
+	return NewTX(openStub(t, stub)), stub
+}
+
+// newReplicaStub TX with one replica
+func newReplicaStub(t *testing.T, opts ...TXOption) (T *TX, primary *stubDB, replica *stubDB) {
+	t.Helper()
+
+	primary, replica = &stubDB{}, &stubDB{}
+	opts = append(opts, WithReplicas(NewReplicas(openStub(t, replica))))
+
+	return NewTX(openStub(t, primary), opts...), primary, replica
+}
+
+func openStub(t *testing.T, stub *stubDB) *sql.DB {
+	t.Helper()
+
+	db := sql.OpenDB(stub)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db
+}
+
+func (s *stubDB) Connect(context.Context) (driver.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connects++
+	if s.connErr != nil {
+		return nil, s.connErr
+	}
+
+	return &stubConn{db: s}, nil
+}
+
+func (s *stubDB) Driver() driver.Driver {
+	return nil
+}
+
+func (s *stubDB) record(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.log = append(s.log, query)
+}
+
+func (s *stubDB) statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.log...)
+}
+
+func (s *stubDB) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.connects
+}
+
+func (c *stubConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c *stubConn) Close() error {
+	return nil
+}
+
+func (c *stubConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *stubConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.db.record("BEGIN READ ONLY")
+	} else {
+		c.db.record("BEGIN")
+	}
+
+	return &stubTx{db: c.db}, nil
+}
+
+func (c *stubConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query)
+	return stubResult{}, nil
+}
+
+func (t *stubTx) Commit() error {
+	t.db.record("COMMIT")
+	return nil
+}
+
+func (t *stubTx) Rollback() error {
+	t.db.record("ROLLBACK")
+	return nil
+}
+
+func (stubResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (stubResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func (e stubSQLError) Error() string {
+	return "sqlstate " + e.state
+}
+
+func (e stubSQLError) SQLState() string {
+	return e.state
+}
+
+func (o *stubObserver) TxRetry(_ string, attempt int, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.retries = append(o.retries, attempt)
+}
+
+func (o *stubObserver) TxGiveUp(_ string, attempts int, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.giveUps = append(o.giveUps, attempts)
+}
+
+func (o *stubObserver) TxDone(label string, _ time.Duration, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.labels = append(o.labels, label)
+}
+
+func exec(T *TX, query string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := T.DB(ctx).ExecContext(ctx, query)
+		return err
+	}
+}
+
+func read(T *TX, query string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := T.ReadDB(ctx).ExecContext(ctx, query)
+		return err
+	}
+}
+
+func assertStatements(t *testing.T, stub *stubDB, want []string) {
+	t.Helper()
+
+	if got := stub.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestTX_SavepointReleased(t *testing.T) {
+	T, stub := newStub(t)
+
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		if err := exec(T, "INSERT outer")(ctx); err != nil {
+			return err
+		}
+
+		return T.TX(ctx, exec(T, "INSERT inner"))
+	})
+	if err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"INSERT outer",
+		"SAVEPOINT tx_savepoint_1",
+		"INSERT inner",
+		"RELEASE SAVEPOINT tx_savepoint_1",
+		"COMMIT",
+	})
+}
+
+func TestTX_SavepointRolledBack(t *testing.T) {
+	T, stub := newStub(t)
+
+	var committed, rolledBack bool
+
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		errInner := T.TX(ctx, func(ctx context.Context) error {
+			OnCommit(ctx, func(context.Context) { committed = true })
+			OnRollback(ctx, func(context.Context) { rolledBack = true })
+
+			if err := exec(T, "INSERT inner")(ctx); err != nil {
+				return err
+			}
+
+			return errProcess
+		})
+		if !errors.Is(errInner, errProcess) {
+			t.Errorf("inner TX: got %v, want %v", errInner, errProcess)
+		}
+
+		// outer transaction goes on after savepoint rollback
+		return exec(T, "INSERT outer")(ctx)
+	})
+	if err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"SAVEPOINT tx_savepoint_1",
+		"INSERT inner",
+		"ROLLBACK TO SAVEPOINT tx_savepoint_1",
+		"INSERT outer",
+		"COMMIT",
+	})
+
+	if committed {
+		t.Error("commit hook of rolled back savepoint is called")
+	}
+
+	if !rolledBack {
+		t.Error("rollback hook of rolled back savepoint is not called")
+	}
+}
+
+func TestTX_OuterRollbackDiscardsRelease(t *testing.T) {
+	T, stub := newStub(t)
+
+	var committed, rolledBack bool
+
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		err := T.TX(ctx, func(ctx context.Context) error {
+			OnCommit(ctx, func(context.Context) { committed = true })
+			OnRollback(ctx, func(context.Context) { rolledBack = true })
+
+			return exec(T, "INSERT inner")(ctx)
+		})
+		if err != nil {
+			return err
+		}
+
+		return errProcess
+	})
+	if !errors.Is(err, errProcess) {
+		t.Fatalf("TX: got %v, want %v", err, errProcess)
+	}
+
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"SAVEPOINT tx_savepoint_1",
+		"INSERT inner",
+		"RELEASE SAVEPOINT tx_savepoint_1",
+		"ROLLBACK",
+	})
+
+	if committed {
+		t.Error("commit hook of released savepoint is called after outer rollback")
+	}
+
+	if !rolledBack {
+		t.Error("rollback hook of released savepoint is not called after outer rollback")
+	}
+}
+
+func TestTX_SavepointNames(t *testing.T) {
+	T, stub := newStub(t)
+
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		if err := T.TX(ctx, exec(T, "INSERT first")); err != nil {
+			return err
+		}
+
+		return T.TX(ctx, func(ctx context.Context) error {
+			return T.TX(ctx, exec(T, "INSERT nested"))
+		})
+	})
+	if err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"SAVEPOINT tx_savepoint_1",
+		"INSERT first",
+		"RELEASE SAVEPOINT tx_savepoint_1",
+		"SAVEPOINT tx_savepoint_2",
+		"SAVEPOINT tx_savepoint_3",
+		"INSERT nested",
+		"RELEASE SAVEPOINT tx_savepoint_3",
+		"RELEASE SAVEPOINT tx_savepoint_2",
+		"COMMIT",
+	})
+}
+
+func TestTX_Strict(t *testing.T) {
+	T, stub := newStub(t)
+
+	var called bool
+
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		return T.TX(ctx, func(context.Context) error {
+			called = true
+			return nil
+		}, WithStrict())
+	})
+	if !errors.Is(err, ErrTxOpenAlready) {
+		t.Fatalf("TX: got %v, want %v", err, ErrTxOpenAlready)
+	}
+
+	if called {
+		t.Error("processor of strict nested TX is called")
+	}
+
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"ROLLBACK",
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "serialization failure", err: stubSQLError{state: "40001"}, want: true},
+		{name: "deadlock", err: stubSQLError{state: "40P01"}, want: true},
+		{name: "wrapped", err: fmt.Errorf("process tx: %w", stubSQLError{state: "40001"}), want: true},
+		{name: "unique violation", err: stubSQLError{state: "23505"}, want: false},
+		{name: "no sqlstate", err: errProcess, want: false},
+		{name: "nil", err: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v): got %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTX_Retry(t *testing.T) {
+	stub := &stubDB{}
+	observer := &stubObserver{}
+	T := NewTX(openStub(t, stub), WithObserver(observer))
+
+	attempts := 0
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errSerialization
+		}
+
+		return exec(T, "INSERT")(ctx)
+	}, WithRetry(3), WithRetryBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"ROLLBACK",
+		"BEGIN",
+		"ROLLBACK",
+		"BEGIN",
+		"INSERT",
+		"COMMIT",
+	})
+
+	if want := []int{1, 2}; !reflect.DeepEqual(observer.retries, want) {
+		t.Errorf("retries: got %v, want %v", observer.retries, want)
+	}
+
+	if len(observer.giveUps) != 0 {
+		t.Errorf("give ups: got %v, want none", observer.giveUps)
+	}
+}
+
+func TestTX_RetryGiveUp(t *testing.T) {
+	stub := &stubDB{}
+	observer := &stubObserver{}
+	T := NewTX(openStub(t, stub), WithObserver(observer))
+
+	attempts := 0
+	err := T.TX(context.Background(), func(context.Context) error {
+		attempts++
+		return errSerialization
+	}, WithRetry(2), WithRetryBackoff(time.Millisecond, time.Millisecond))
+	if !IsRetryable(err) {
+		t.Fatalf("TX: got %v, want serialization failure", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("attempts: got %d, want 2", attempts)
+	}
+
+	if want := []int{2}; !reflect.DeepEqual(observer.giveUps, want) {
+		t.Errorf("give ups: got %v, want %v", observer.giveUps, want)
+	}
+}
+
+func TestTX_RetryNotRetryable(t *testing.T) {
+	T, _ := newStub(t)
+
+	attempts := 0
+	err := T.TX(context.Background(), func(context.Context) error {
+		attempts++
+		return errProcess
+	}, WithRetry(3))
+	if !errors.Is(err, errProcess) {
+		t.Fatalf("TX: got %v, want %v", err, errProcess)
+	}
+
+	if attempts != 1 {
+		t.Errorf("attempts: got %d, want 1", attempts)
+	}
+}
+
+func TestTX_RetryBackoffCanceled(t *testing.T) {
+	T, _ := newStub(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := 0
+	err := T.TX(ctx, func(context.Context) error {
+		attempts++
+		cancel()
+		return errSerialization
+	}, WithRetry(3), WithRetryBackoff(time.Hour, time.Hour))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("TX: got %v, want %v", err, context.Canceled)
+	}
+
+	if attempts != 1 {
+		t.Errorf("attempts: got %d, want 1", attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	const (
+		base     = 10 * time.Millisecond
+		maxDelay = 50 * time.Millisecond
+	)
+
+	limits := []time.Duration{10, 20, 40, 50, 50}
+	for i, limit := range limits {
+		attempt := i + 1
+		limit *= time.Millisecond
+
+		for j := 0; j < 100; j++ {
+			if got := backoff(attempt, base, maxDelay); got <= 0 || got > limit {
+				t.Fatalf("backoff(%d): got %v, want in (0, %v]", attempt, got, limit)
+			}
+		}
+	}
+
+	if got := backoff(1, 0, maxDelay); got != 0 {
+		t.Errorf("backoff with zero base: got %v, want 0", got)
+	}
+}
+
+func TestTX_ReadDBReplica(t *testing.T) {
+	T, primary, replica := newReplicaStub(t)
+	ctx := context.Background()
+
+	if err := read(T, "SELECT read")(ctx); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	// read only context routes DB to replica
+	if err := exec(T, "SELECT marked")(MarkReadOnly(ctx)); err != nil {
+		t.Fatalf("read only DB: %v", err)
+	}
+
+	if err := exec(T, "UPDATE")(ctx); err != nil {
+		t.Fatalf("DB: %v", err)
+	}
+
+	assertStatements(t, replica, []string{"SELECT read", "SELECT marked"})
+	assertStatements(t, primary, []string{"UPDATE"})
+}
+
+func TestTX_ReadDBSticky(t *testing.T) {
+	T, primary, replica := newReplicaStub(t, WithStickiness(time.Minute))
+
+	writer := WithStickyKey(context.Background(), "writer")
+	committer := WithStickyKey(context.Background(), "committer")
+	reader := WithStickyKey(context.Background(), "reader")
+
+	if _, err := T.WriteDB(writer).ExecContext(writer, "UPDATE writer"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := T.TX(committer, exec(T, "UPDATE committer")); err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	// DB out of transaction is not an explicit write
+	if err := exec(T, "SELECT reader")(reader); err != nil {
+		t.Fatalf("DB: %v", err)
+	}
+
+	for _, ctx := range []context.Context{writer, committer, reader} {
+		if err := read(T, "SELECT "+ctxStickyKey(ctx))(ctx); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	}
+
+	assertStatements(t, primary, []string{
+		"UPDATE writer",
+		"BEGIN",
+		"UPDATE committer",
+		"COMMIT",
+		"SELECT reader",
+		"SELECT writer",
+		"SELECT committer",
+	})
+	assertStatements(t, replica, []string{"SELECT reader"})
+}
+
+func TestTX_ReadDBFallback(t *testing.T) {
+	T, primary, replica := newReplicaStub(t)
+	replica.connErr = driver.ErrBadConn
+
+	ctx := context.Background()
+
+	if err := read(T, "SELECT first")(ctx); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	connects := replica.connections()
+	if connects == 0 {
+		t.Fatal("replica is not tried")
+	}
+
+	// failed replica is skipped until health check
+	if err := read(T, "SELECT second")(ctx); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if got := replica.connections(); got != connects {
+		t.Errorf("replica connections: got %d, want %d", got, connects)
+	}
+
+	assertStatements(t, primary, []string{"SELECT first", "SELECT second"})
+	assertStatements(t, replica, nil)
+}
+
+func TestTX_ReadOnly(t *testing.T) {
+	T, primary, replica := newReplicaStub(t, WithStickiness(time.Minute))
+	ctx := WithStickyKey(context.Background(), "reader")
+
+	if err := T.TX(ctx, exec(T, "SELECT tx"), ReadOnly()); err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	// read only transaction is not a write
+	if err := read(T, "SELECT after")(ctx); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	assertStatements(t, replica, []string{
+		"BEGIN READ ONLY",
+		"SELECT tx",
+		"COMMIT",
+		"SELECT after",
+	})
+	assertStatements(t, primary, nil)
+}
+
+func TestTX_ReadOnlyFallback(t *testing.T) {
+	T, primary, replica := newReplicaStub(t)
+	replica.connErr = driver.ErrBadConn
+
+	if err := T.TX(context.Background(), exec(T, "SELECT tx"), ReadOnly()); err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	assertStatements(t, primary, []string{
+		"BEGIN READ ONLY",
+		"SELECT tx",
+		"COMMIT",
+	})
+	assertStatements(t, replica, nil)
+}
+
+func TestTX_WithTimeout(t *testing.T) {
+	T, stub := newStub(t)
+
+	var deadline bool
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		_, deadline = ctx.Deadline()
+		return nil
+	}, WithTimeout(1500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	if !deadline {
+		t.Error("transaction context has no deadline")
+	}
+
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"SET LOCAL statement_timeout = 1500",
+		"SET LOCAL idle_in_transaction_session_timeout = 1500",
+		"COMMIT",
+	})
+}
+
+func TestSetTimeouts_BelowMillisecond(t *testing.T) {
+	stub := &stubDB{}
+	db := openStub(t, stub)
+
+	// context without deadline, timeout below millisecond expires before statements
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if err = setTimeouts(context.Background(), tx, time.Microsecond); err != nil {
+		t.Fatalf("setTimeouts: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	// 0 disables Postgres timeouts, rounded up to 1
+	assertStatements(t, stub, []string{
+		"BEGIN",
+		"SET LOCAL statement_timeout = 1",
+		"SET LOCAL idle_in_transaction_session_timeout = 1",
+		"COMMIT",
+	})
+}
+
+func TestTX_WithLabel(t *testing.T) {
+	stub := &stubDB{}
+	observer := &stubObserver{}
+	T := NewTX(openStub(t, stub), WithObserver(observer))
+
+	var outer, nested string
+	err := T.TX(context.Background(), func(ctx context.Context) error {
+		outer = Label(ctx)
+
+		// options of nested call are ignored
+		return T.TX(ctx, func(ctx context.Context) error {
+			nested = Label(ctx)
+			return nil
+		}, WithLabel("nested"))
+	}, WithLabel("outer"))
+	if err != nil {
+		t.Fatalf("TX: %v", err)
+	}
+
+	if outer != "outer" || nested != "outer" {
+		t.Errorf("Label: got %q and nested %q, want %q", outer, nested, "outer")
+	}
+
+	if got := Label(context.Background()); got != "" {
+		t.Errorf("Label out of transaction: got %q, want empty", got)
+	}
+
+	if want := []string{"outer"}; !reflect.DeepEqual(observer.labels, want) {
+		t.Errorf("observer labels: got %v, want %v", observer.labels, want)
+	}
+}